@@ -3,6 +3,9 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
 
 	"github.com/jesusangelm/api_galeria/internal/data"
 	"github.com/jesusangelm/api_galeria/internal/validator"
@@ -14,6 +17,7 @@ func (app *application) createAdminUser(w http.ResponseWriter, r *http.Request)
 		LastName  string `json:"last_name"`
 		Email     string `json:"email"`
 		Password  string `json:"password"`
+		Role      string `json:"role"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -27,6 +31,12 @@ func (app *application) createAdminUser(w http.ResponseWriter, r *http.Request)
 		LastName:  input.LastName,
 		Email:     input.Email,
 		Activated: false,
+		Role:      input.Role,
+	}
+
+	// the least privileged role unless another one is given
+	if adminUser.Role == "" {
+		adminUser.Role = data.RoleViewer
 	}
 
 	err = adminUser.Password.Set(input.Password)
@@ -54,7 +64,384 @@ func (app *application) createAdminUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"admin_user": adminUser}, nil)
+	// the new admin user can not log in until the token is used
+	token, err := app.models.Tokens.New(adminUser.ID, activationTokenTTL, data.ScopeActivation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"admin_user": adminUser, "activation_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Activate the admin user of an activation token, the token is consumed
+func (app *application) activateAdminUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	adminUser.Activated = true
+
+	err = app.models.AdminUser.Update(adminUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForAdminUser(data.ScopeActivation, adminUser.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"admin_user": adminUser}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAdminUsers(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Search string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query() // To get filter parameters from the QueryString
+
+	input.Search = app.readString(qs, "search", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.SortSafeList = []string{
+		"id", "email", "created_at", "-id", "-email", "-created_at",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	adminUsers, metadata, err := app.models.AdminUser.List(input.Search, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"admin_users": adminUsers, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showAdminUser(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetById(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"admin_user": adminUser}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Update the profile or the role of an admin user or (de)activate it. A
+// deactivated admin user is rejected by authRequired from its next request,
+// and after a role change its tokens must be refreshed.
+func (app *application) updateAdminUser(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetById(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// we use pointers here for support partial update
+	var input struct {
+		FirstName   *string `json:"first_name"`
+		LastName    *string `json:"last_name"`
+		Email       *string `json:"email"`
+		Role        *string `json:"role"`
+		Deactivated *bool   `json:"deactivated"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.FirstName != nil {
+		adminUser.FirstName = *input.FirstName
+	}
+	if input.LastName != nil {
+		adminUser.LastName = *input.LastName
+	}
+	if input.Email != nil {
+		adminUser.Email = *input.Email
+	}
+
+	v := validator.New()
+
+	if input.Role != nil {
+		// an admin user can not change its own role, e.g. the last owner
+		v.Check(*input.Role == adminUser.Role || id != app.contextGetAdmin(r).ID, "role", "must not be changed for your own admin user")
+
+		adminUser.Role = *input.Role
+	}
+
+	if input.Deactivated != nil {
+		// an admin user can not lock itself out
+		v.Check(!*input.Deactivated || id != app.contextGetAdmin(r).ID, "deactivated", "must not be your own admin user")
+
+		switch {
+		case *input.Deactivated && !adminUser.Deactivated():
+			now := time.Now()
+			adminUser.DeactivatedAt = &now
+		case !*input.Deactivated:
+			adminUser.DeactivatedAt = nil
+		}
+	}
+
+	if data.ValidateUser(v, adminUser); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.AdminUser.Update(adminUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a admin user with this email already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"admin_user": adminUser}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAdminUser(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// an admin user can not lock itself out
+	if id == app.contextGetAdmin(r).ID {
+		app.badRequestResponse(w, r, errors.New("you can not delete your own admin user"))
+		return
+	}
+
+	err = app.models.AdminUser.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "admin user successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Change the password of the authenticated admin user. The route is
+// /v1/admin_users/:id/password because httprouter does not allow a "me"
+// segment next to the :id parameter, so only "me" is accepted as the id.
+func (app *application) updateAdminUserPassword(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	if params.ByName("id") != "me" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	adminUser := app.contextGetAdmin(r)
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.CurrentPassword != "", "current_password", "must be provided")
+	data.ValidatePasswordPlaintext(v, input.Password)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	match, err := adminUser.Password.Matches(input.CurrentPassword)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		v.AddError("current_password", "does not match your password")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = adminUser.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.AdminUser.Update(adminUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "password successfully updated"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Set a new password with a password reset token, the token is consumed
+func (app *application) resetAdminUserPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password       string `json:"password"`
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidatePasswordPlaintext(v, input.Password)
+	data.ValidateTokenPlaintext(v, input.TokenPlaintext)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetForToken(data.ScopePasswordReset, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = adminUser.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.AdminUser.Update(adminUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForAdminUser(data.ScopePasswordReset, adminUser.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
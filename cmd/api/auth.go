@@ -25,6 +25,7 @@ type jwtAdmin struct {
 	ID        int64  `json:"id"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	Role      string `json:"role"`
 }
 
 type TokenPairs struct {
@@ -34,6 +35,8 @@ type TokenPairs struct {
 
 type Claims struct {
 	jwt.RegisteredClaims
+	// role of the admin user, only in the access token
+	Role string `json:"role"`
 }
 
 func (j *Auth) GenerateTokenPair(user *jwtAdmin) (TokenPairs, error) {
@@ -44,6 +47,7 @@ func (j *Auth) GenerateTokenPair(user *jwtAdmin) (TokenPairs, error) {
 	claims := token.Claims.(jwt.MapClaims)
 	claims["name"] = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
 	claims["sub"] = fmt.Sprint(user.ID)
+	claims["role"] = user.Role
 	claims["aud"] = j.Audience
 	claims["iss"] = j.Issuer
 	claims["iat"] = time.Now().UTC().Unix()
@@ -58,11 +58,22 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if adminUser.Deactivated() {
+		app.deactivatedAccountResponse(w, r)
+		return
+	}
+
+	if !adminUser.Activated {
+		app.inactiveAccountResponse(w, r)
+		return
+	}
+
 	// create a jwt adminUser
 	au := jwtAdmin{
 		ID:        adminUser.ID,
 		FirstName: adminUser.FirstName,
 		LastName:  adminUser.LastName,
+		Role:      adminUser.Role,
 	}
 
 	// generate tokens
@@ -109,10 +120,21 @@ func (app *application) refreshToken(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if adminUser.Deactivated() {
+				app.deactivatedAccountResponse(w, r)
+				return
+			}
+
+			if !adminUser.Activated {
+				app.inactiveAccountResponse(w, r)
+				return
+			}
+
 			au := jwtAdmin{
 				ID:        adminUser.ID,
 				FirstName: adminUser.FirstName,
 				LastName:  adminUser.LastName,
+				Role:      adminUser.Role,
 			}
 
 			tokenPairs, err := app.config.auth.GenerateTokenPair(&au)
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+)
+
+// Format of the backup archives, increased on incompatible changes
+const backupFormatVersion = 1
+
+// Time limit for a backup or a restore
+const backupTimeout = time.Hour
+
+// The manifest.json of a backup archive. The archive has a tables/<table>.ndjson
+// file per table, with a JSON object per row, and an objects/<key> file per
+// file in S3. The checksums of all of them are in the manifest.
+type backupManifest struct {
+	FormatVersion int                   `json:"format_version"`
+	AppVersion    string                `json:"app_version"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Tables        []string              `json:"tables"`
+	Objects       []string              `json:"objects"`
+	Files         map[string]backupFile `json:"files"` // by name in the archive
+}
+
+type backupFile struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+func backupTableFile(table string) string { return "tables/" + table + ".ndjson" }
+func backupObjectFile(key string) string  { return "objects/" + key }
+
+// Write a backup of the database and the files in S3 to the given path.
+// The archive is written to a temporary file renamed once it is complete.
+func (app *application) backup(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path + ".tmp") // fails once renamed
+	defer file.Close()
+
+	// the tables and the keys are read from a single snapshot
+	snapshot, err := app.models.Backup.BeginSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	// read only, there is nothing to commit
+	defer snapshot.Rollback(ctx)
+
+	zw := zip.NewWriter(file)
+
+	manifest := backupManifest{
+		FormatVersion: backupFormatVersion,
+		AppVersion:    version,
+		CreatedAt:     time.Now().UTC(),
+		Tables:        data.BackupTables,
+		Files:         make(map[string]backupFile),
+	}
+
+	// create an entry of the archive, its checksum is saved when done is called
+	create := func(name string, method uint16) (io.Writer, func(), error) {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: manifest.CreatedAt})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cw := &checksumWriter{hash: sha256.New()}
+		done := func() { manifest.Files[name] = cw.file() }
+
+		return io.MultiWriter(fw, cw), done, nil
+	}
+
+	for _, table := range data.BackupTables {
+		w, done, err := create(backupTableFile(table), zip.Deflate)
+		if err != nil {
+			return err
+		}
+
+		rows := 0
+		err = app.models.Backup.DumpTable(ctx, snapshot, table, func(row []byte) error {
+			rows++
+			_, err := w.Write(append(row, '\n'))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("dumping %s: %w", table, err)
+		}
+		done()
+
+		app.logger.PrintInfo("table saved", map[string]string{"table": table, "rows": fmt.Sprint(rows)})
+	}
+
+	manifest.Objects, err = app.models.Backup.StorageKeys(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range manifest.Objects {
+		// the images are already compressed
+		w, done, err := create(backupObjectFile(key), zip.Store)
+		if err != nil {
+			return err
+		}
+
+		object, err := app.s3Manager.GetFile(ctx, key)
+		if err != nil {
+			return fmt.Errorf("downloading %s: %w", key, err)
+		}
+
+		_, err = io.Copy(w, object)
+		object.Close()
+		if err != nil {
+			return fmt.Errorf("downloading %s: %w", key, err)
+		}
+		done()
+	}
+
+	app.logger.PrintInfo("files saved", map[string]string{"files": fmt.Sprint(len(manifest.Objects))})
+
+	fw, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "\t")
+
+	err = enc.Encode(manifest)
+	if err != nil {
+		return err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Restore the backup at the given path in an empty database. The checksums
+// of the whole archive are verified before anything is written, then the
+// files are uploaded to S3 and the tables restored in a single transaction.
+func (app *application) restore(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	manifest, err := readBackupManifest(archive)
+	if err != nil {
+		return err
+	}
+
+	for name, expected := range manifest.Files {
+		err = verifyBackupFile(archive, name, expected, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	app.logger.PrintInfo("backup verified", map[string]string{
+		"created_at": manifest.CreatedAt.Format(time.RFC3339),
+		"files":      fmt.Sprint(len(manifest.Files)),
+	})
+
+	// checked before uploading the files, and again in the restore transaction
+	err = app.models.Backup.CheckEmpty(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range manifest.Objects {
+		name := backupObjectFile(key)
+
+		var content []byte
+		err = verifyBackupFile(archive, name, manifest.Files[name], func(r io.Reader) error {
+			var err error
+			content, err = io.ReadAll(r)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		err = app.s3Manager.PutFile(key, content)
+		if err != nil {
+			return fmt.Errorf("uploading %s: %w", key, err)
+		}
+	}
+
+	app.logger.PrintInfo("files restored", map[string]string{"files": fmt.Sprint(len(manifest.Objects))})
+
+	err = app.models.Backup.Restore(ctx, func(table string, insert func(row []byte) error) error {
+		name := backupTableFile(table)
+
+		return verifyBackupFile(archive, name, manifest.Files[name], func(r io.Reader) error {
+			scanner := bufio.NewScanner(r)
+			// a row may be larger than the default limit of a line
+			scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+			for scanner.Scan() {
+				err := insert(scanner.Bytes())
+				if err != nil {
+					return err
+				}
+			}
+
+			return scanner.Err()
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("database restored", nil)
+
+	return nil
+}
+
+// Read the manifest of the archive, checking that it lists every table
+func readBackupManifest(archive *zip.ReadCloser) (*backupManifest, error) {
+	file, err := archive.Open("manifest.json")
+	if err != nil {
+		return nil, errors.New("invalid backup: missing manifest.json")
+	}
+	defer file.Close()
+
+	var manifest backupManifest
+
+	err = json.NewDecoder(file).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	if manifest.FormatVersion != backupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	for _, table := range data.BackupTables {
+		if _, ok := manifest.Files[backupTableFile(table)]; !ok {
+			return nil, fmt.Errorf("invalid backup: missing table %s", table)
+		}
+	}
+
+	for _, key := range manifest.Objects {
+		if _, ok := manifest.Files[backupObjectFile(key)]; !ok {
+			return nil, fmt.Errorf("invalid backup: missing file %s", key)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// Read the file of the archive with fn, when not nil, and check its checksum.
+// fn must read the whole file.
+func verifyBackupFile(archive *zip.ReadCloser, name string, expected backupFile, fn func(io.Reader) error) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("invalid backup: missing %s", name)
+	}
+	defer file.Close()
+
+	cw := &checksumWriter{hash: sha256.New()}
+	r := io.TeeReader(file, cw)
+
+	if fn == nil {
+		_, err = io.Copy(io.Discard, r)
+	} else {
+		err = fn(r)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	if cw.file() != expected {
+		return fmt.Errorf("invalid backup: checksum mismatch for %s", name)
+	}
+
+	return nil
+}
+
+// io.Writer computing the checksum and size of what is written
+type checksumWriter struct {
+	hash hash.Hash
+	size int64
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	cw.size += int64(len(p))
+	return cw.hash.Write(p)
+}
+
+func (cw *checksumWriter) file() backupFile {
+	return backupFile{SHA256: hex.EncodeToString(cw.hash.Sum(nil)), Size: cw.size}
+}
@@ -13,8 +13,9 @@ func (app *application) createCategory(w http.ResponseWriter, r *http.Request) {
 	// declare a struct to hold the information we expect to receive
 	// this struct will be the target decode destination
 	var input struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
+		Name            string                     `json:"name"`
+		Description     string                     `json:"description"`
+		AttributeSchema []data.AttributeDefinition `json:"attribute_schema"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -24,8 +25,9 @@ func (app *application) createCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	category := &data.Category{
-		Name:        input.Name,
-		Description: input.Description,
+		Name:            input.Name,
+		Description:     input.Description,
+		AttributeSchema: input.AttributeSchema,
 	}
 
 	v := validator.New()
@@ -35,7 +37,7 @@ func (app *application) createCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.models.Categories.Insert(category)
+	err = app.models.Categories.Insert(category, app.contextGetAdmin(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateName):
@@ -64,7 +66,7 @@ func (app *application) showCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	category, err := app.models.Categories.Get(id)
+	category, err := app.models.Categories.GetLocalized(id, app.readLocale(r))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -75,6 +77,9 @@ func (app *application) showCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the content depends on the requested locale
+	w.Header().Add("Vary", "Accept-Language")
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -101,8 +106,9 @@ func (app *application) updateCategory(w http.ResponseWriter, r *http.Request) {
 
 	// we use pointers here for support partial update
 	var input struct {
-		Name        *string `json:"name"`
-		Description *string `json:"description"`
+		Name            *string                    `json:"name"`
+		Description     *string                    `json:"description"`
+		AttributeSchema []data.AttributeDefinition `json:"attribute_schema"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -117,6 +123,9 @@ func (app *application) updateCategory(w http.ResponseWriter, r *http.Request) {
 	if input.Description != nil {
 		category.Description = *input.Description
 	}
+	if input.AttributeSchema != nil {
+		category.AttributeSchema = input.AttributeSchema
+	}
 
 	v := validator.New()
 
@@ -125,7 +134,7 @@ func (app *application) updateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.models.Categories.Update(category)
+	err = app.models.Categories.Update(category, app.contextGetAdmin(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -142,6 +151,92 @@ func (app *application) updateCategory(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (app *application) reorderCategoryItems(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// version is the category version the client based the order on. The
+	// order is given as item_ids, or as items with their versions to also
+	// check that the items did not change.
+	var input struct {
+		ItemIDs []int64          `json:"item_ids"`
+		Items   []data.ItemOrder `json:"items"`
+		Version *int32           `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Version != nil, "version", "must be provided")
+	v.Check(input.ItemIDs == nil || input.Items == nil, "items", "must not be provided along with item_ids")
+
+	items := input.Items
+	if input.ItemIDs != nil {
+		items = make([]data.ItemOrder, len(input.ItemIDs))
+		for i, id := range input.ItemIDs {
+			items[i] = data.ItemOrder{ID: id}
+		}
+	}
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		v.Check(item.Version >= 0, "items", "must not contain negative versions")
+	}
+	v.Check(validator.Unique(ids), "item_ids", "must not contain duplicate values")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	category.Version = *input.Version
+
+	err = app.models.Categories.ReorderItems(category, items)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrInvalidItemOrder):
+			v.AddError("item_ids", "must contain exactly the items of the category")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	category, err = app.models.Categories.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) deleteCategory(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -160,7 +255,7 @@ func (app *application) deleteCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully deleted"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully moved to the trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -179,6 +274,7 @@ func (app *application) listCategories(w http.ResponseWriter, r *http.Request) {
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
 	input.Filters.SortSafeList = []string{
 		"id", "name", "created_at", "-id", "-name", "-created_at",
 	}
@@ -188,11 +284,14 @@ func (app *application) listCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	categories, metadata, err := app.models.Categories.List(input.Name, input.Filters)
+	categories, metadata, err := app.models.Categories.List(input.Name, app.readLocale(r), input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
+	// the content depends on the requested locale
+	w.Header().Add("Vary", "Accept-Language")
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
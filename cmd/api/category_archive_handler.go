@@ -0,0 +1,236 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+)
+
+// Number of images downloaded from S3 at the same time for an archive,
+// it also bounds the number of images kept in memory
+const archiveParallelism = 4
+
+// An item in the manifest.json of a category archive
+type archiveManifestItem struct {
+	ID           int64          `json:"id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Price        int64          `json:"price"`
+	Currency     string         `json:"currency"`
+	Availability string         `json:"availability"`
+	Stock        int32          `json:"stock"`
+	Attributes   map[string]any `json:"attributes"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Image        string         `json:"image,omitempty"` // name of the image in the archive
+	Filename     string         `json:"filename,omitempty"`
+}
+
+// An image downloaded for the archive
+type archiveImage struct {
+	content []byte
+	err     error
+}
+
+// Stream a ZIP with the original images of the items of the category and a
+// manifest.json with their metadata. The images are downloaded concurrently
+// and written in order, straight to the response.
+func (app *application) showCategoryArchive(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var items []*data.Item
+
+	err = app.models.Items.Export(data.ItemSearch{CategoryID: int(category.ID)}, func(item *data.Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the archive may take longer than the write timeout of the server
+	err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(data.ExportTimeout))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// stop the downloads when the client goes away or the archive fails
+	ctx, cancel := context.WithTimeout(r.Context(), data.ExportTimeout)
+	defer cancel()
+
+	fetcher := app.fetchArchiveImages(ctx, items)
+
+	w.Header().Set("Content-Type", "application/zip")
+	// the name of the category may not be ASCII, FormatMediaType encodes it
+	filename := slugify(category.Name) + ".zip"
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	zw := zip.NewWriter(w)
+
+	manifest := []archiveManifestItem{}
+
+	for i, item := range items {
+		entry := archiveManifestItem{
+			ID:           item.ID,
+			Name:         item.Name,
+			Description:  item.Description,
+			Price:        item.Price,
+			Currency:     item.Currency,
+			Availability: item.Availability,
+			Stock:        item.Stock,
+			Attributes:   item.Attributes,
+			CreatedAt:    item.CreatedAt,
+			Filename:     item.ItemAttachment.Filename,
+		}
+
+		if item.ItemAttachment.Key != "" {
+			image := fetcher.image(i)
+			if image.err != nil {
+				app.logError(r, image.err)
+				return
+			}
+
+			entry.Image = archiveImageName(item)
+
+			// the images are already compressed
+			fw, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     entry.Image,
+				Method:   zip.Store,
+				Modified: item.CreatedAt,
+			})
+			if err == nil {
+				_, err = fw.Write(image.content)
+			}
+			if err != nil {
+				app.logError(r, err)
+				return
+			}
+		}
+
+		manifest = append(manifest, entry)
+	}
+
+	fw, err := zw.Create("manifest.json")
+	if err == nil {
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// Downloads the images of the items of an archive, at most
+// archiveParallelism at a time. A slot is taken for every download and freed
+// when the image is received, so the pending images are bounded in memory.
+type archiveFetcher struct {
+	images []chan archiveImage // the image of items[i] is sent on images[i]
+	slots  chan struct{}
+}
+
+// Start downloading the images of the items, until ctx is done
+func (app *application) fetchArchiveImages(ctx context.Context, items []*data.Item) *archiveFetcher {
+	fetcher := &archiveFetcher{
+		images: make([]chan archiveImage, len(items)),
+		slots:  make(chan struct{}, archiveParallelism),
+	}
+	for i := range fetcher.images {
+		fetcher.images[i] = make(chan archiveImage, 1)
+	}
+
+	go func() {
+		for i, item := range items {
+			if item.ItemAttachment.Key == "" {
+				continue
+			}
+
+			select {
+			case fetcher.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(key string, result chan<- archiveImage) {
+				content, err := app.fetchArchiveImage(ctx, key)
+				result <- archiveImage{content: content, err: err}
+			}(item.ItemAttachment.Key, fetcher.images[i])
+		}
+	}()
+
+	return fetcher
+}
+
+// Wait for the image of items[i] and free its slot
+func (f *archiveFetcher) image(i int) archiveImage {
+	image := <-f.images[i]
+	<-f.slots
+
+	return image
+}
+
+// Return the content of the image with the given key
+func (app *application) fetchArchiveImage(ctx context.Context, key string) ([]byte, error) {
+	file, err := app.s3Manager.GetFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// Name of the image of the item in the archive, e.g. 12-ceramic-vase.jpg
+func archiveImageName(item *data.Item) string {
+	ext := strings.ToLower(path.Ext(item.ItemAttachment.Filename))
+	return fmt.Sprintf("%d-%s%s", item.ID, slugify(item.Name), ext)
+}
+
+// Return a lowercase version of s with only letters, digits and dashes
+func slugify(s string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Upload a JPEG or PNG image as the cover of the category
+func (app *application) uploadCategoryCover(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Max 10MB files
+	maxUploadSize := 10_485_760 // 10MB
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxUploadSize))
+	if err := r.ParseMultipartForm(int64(maxUploadSize)); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("File must not be larger than %d bytes", maxUploadSize))
+		return
+	}
+
+	file, handler, err := r.FormFile("cover_file")
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("cover_file must be provided"))
+		return
+	}
+	defer file.Close()
+
+	// only JPEG OR PNG allowed
+	fileType := handler.Header.Get("Content-Type")
+	if fileType != "image/jpeg" && fileType != "image/png" {
+		app.badRequestResponse(w, r, fmt.Errorf("File format %s not allowed. Please upload a JPEG or PNG image", fileType))
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	attachment, err := app.s3Manager.UploadFile(file, *handler)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	categoryAttachment := &data.CategoryAttachment{
+		Key:         attachment.Key,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		ByteSize:    attachment.ByteSize,
+		CategoryID:  category.ID,
+	}
+
+	err = app.models.CategoryCovers.Insert(categoryAttachment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	category.CoverURL = app.s3Manager.GetFileUrl(categoryAttachment.Key)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Use the image of one of the items of the category as its cover
+func (app *application) setCategoryCover(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ItemAttachmentID int64 `json:"item_attachment_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.ItemAttachmentID > 0, "item_attachment_id", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.CategoryCovers.SetFromItemAttachment(id, input.ItemAttachmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidAttachment):
+			v.AddError("item_attachment_id", "must be an attachment of an item of the category")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryCover(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.CategoryCovers.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category cover successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	// declare a struct to hold the information we expect to receive
+	// this struct will be the target decode destination
+	var input struct {
+		Name              string  `json:"name"`
+		Description       string  `json:"description"`
+		CoverAttachmentID *int64  `json:"cover_attachment_id"`
+		Published         bool    `json:"published"`
+		ItemIDs           []int64 `json:"item_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	collection := &data.Collection{
+		Name:              input.Name,
+		Description:       input.Description,
+		CoverAttachmentID: input.CoverAttachmentID,
+		Published:         input.Published,
+		ItemIDs:           input.ItemIDs,
+	}
+
+	v := validator.New()
+
+	if data.ValidateCollection(v, collection); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collections.Insert(collection)
+	if err != nil {
+		app.collectionWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	// utility header
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/collections/%d", collection.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"collection": collection}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collection": collection}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// we use pointers here for support partial update
+	var input struct {
+		Name              *string `json:"name"`
+		Description       *string `json:"description"`
+		CoverAttachmentID *int64  `json:"cover_attachment_id"`
+		Published         *bool   `json:"published"`
+		ItemIDs           []int64 `json:"item_ids"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		collection.Name = *input.Name
+	}
+	if input.Description != nil {
+		collection.Description = *input.Description
+	}
+	if input.CoverAttachmentID != nil {
+		collection.CoverAttachmentID = input.CoverAttachmentID
+	}
+	if input.Published != nil {
+		collection.Published = *input.Published
+	}
+	if input.ItemIDs != nil {
+		collection.ItemIDs = input.ItemIDs
+	}
+
+	v := validator.New()
+
+	if data.ValidateCollection(v, collection); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the items are only replaced when given, so the trashed items are kept
+	err = app.models.Collections.Update(collection, input.ItemIDs != nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.collectionWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	// reload to return the items in their new order
+	collection, err = app.models.Collections.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collection": collection}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Collections.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "collection successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listCollections(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string
+		Published *bool
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query() // To get filter parameters from the QueryString
+
+	input.Name = app.readString(qs, "name", "")
+	if qs.Has("published") {
+		published := app.readBool(qs, "published", false, v)
+		input.Published = &published
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.SortSafeList = []string{
+		"id", "name", "created_at", "-id", "-name", "-created_at",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	collections, metadata, err := app.models.Collections.List(input.Name, input.Published, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collections": collections, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Send the response for the errors of inserting or updating a collection
+func (app *application) collectionWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateName):
+		v.AddError("name", "a collection with this name already exists")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrInvalidAttachment):
+		v.AddError("cover_attachment_id", "must be an existing item attachment")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrInvalidCollectionItems):
+		v.AddError("item_ids", "must contain only existing items")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+)
+
+type contextKey string
+
+const adminUserContextKey = contextKey("adminUser")
+
+// Return a copy of the request with the authenticated admin user
+func (app *application) contextSetAdmin(r *http.Request, adminUser *data.AdminUser) *http.Request {
+	ctx := context.WithValue(r.Context(), adminUserContextKey, adminUser)
+	return r.WithContext(ctx)
+}
+
+// Return the authenticated admin user, set by the authRequired middleware
+func (app *application) contextGetAdmin(r *http.Request) *data.AdminUser {
+	adminUser, ok := r.Context().Value(adminUserContextKey).(*data.AdminUser)
+	if !ok {
+		panic("missing admin user in request context")
+	}
+
+	return adminUser
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Columns of the CSV export of the items
+var exportItemsColumns = []string{
+	"id", "name", "description", "category_id", "category_name", "price", "currency",
+	"availability", "stock", "created_at", "filename", "key", "image_url",
+}
+
+// Stream every item matching the filters of the items listing as CSV or
+// NDJSON. The response is written row by row, so once the export started an
+// error can only be logged.
+func (app *application) exportItems(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	search := app.readItemSearch(qs, v)
+	search.Locale = app.readLocale(r)
+	format := app.readString(qs, "format", "csv")
+
+	v.Check(validator.PermittedValue(format, "csv", "ndjson"), "format", "must be csv or ndjson")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the export may take longer than the write timeout of the server
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(data.ExportTimeout))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="items.csv"`)
+
+		cw := csv.NewWriter(w)
+
+		err = cw.Write(exportItemsColumns)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		err = app.models.Items.Export(search, func(item *data.Item) error {
+			return cw.Write([]string{
+				strconv.FormatInt(item.ID, 10),
+				item.Name,
+				item.Description,
+				strconv.FormatInt(item.CategoryID, 10),
+				item.CategoryName,
+				strconv.FormatInt(item.Price, 10),
+				item.Currency,
+				item.Availability,
+				strconv.FormatInt(int64(item.Stock), 10),
+				item.CreatedAt.Format(time.RFC3339),
+				item.ItemAttachment.Filename,
+				item.ItemAttachment.Key,
+				item.ImageURL,
+			})
+		})
+
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="items.ndjson"`)
+
+		enc := json.NewEncoder(w)
+
+		err = app.models.Items.Export(search, func(item *data.Item) error {
+			return enc.Encode(item)
+		})
+	}
+
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -12,17 +12,23 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 
+	"github.com/jesusangelm/api_galeria/internal/data"
 	"github.com/jesusangelm/api_galeria/internal/validator"
 )
 
 type envelope map[string]any
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+// Read an id from the URL parameter with the given name, e.g. "variant_id"
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id paramenter")
+		return 0, fmt.Errorf("invalid %s paramenter", name)
 	}
 
 	return id, nil
@@ -122,6 +128,20 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+// Return the query string values whose key starts with prefix, keyed
+// without the prefix, e.g. "attr.material=wool" as {"material": "wool"}
+func (app *application) readPrefixedMap(qs url.Values, prefix string) map[string]string {
+	values := make(map[string]string)
+
+	for key := range qs {
+		if name, found := strings.CutPrefix(key, prefix); found && name != "" {
+			values[name] = qs.Get(key)
+		}
+	}
+
+	return values
+}
+
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	s := qs.Get(key)
 
@@ -138,6 +158,51 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// Return the locale requested with the lang query string parameter or the
+// Accept-Language header, or the default locale when none is supported
+func (app *application) readLocale(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); validator.PermittedValue(lang, data.Locales...) {
+		return lang
+	}
+
+	// e.g. "en-US,en;q=0.9,es;q=0.8"
+	locale, bestQuality := data.DefaultLocale, 0.0
+
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality > bestQuality && validator.PermittedValue(lang, data.Locales...) {
+			locale, bestQuality = lang, quality
+		}
+	}
+
+	return locale
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
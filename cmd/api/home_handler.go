@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+)
+
+func (app *application) showHome(w http.ResponseWriter, r *http.Request) {
+	home, err := app.models.Home.Get(app.readLocale(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the content depends on the requested locale
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"home": home}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+)
+
+// Start the import of a ZIP with a manifest.csv and the images of the items.
+// The manifest is checked before answering, the rows are processed by a
+// background job whose progress and report are available at /v1/import/:job_id.
+func (app *application) createImport(w http.ResponseWriter, r *http.Request) {
+	// Max 200MB archives, the parts larger than 32MB are kept on disk
+	maxUploadSize := 209_715_200 // 200MB
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxUploadSize))
+	if err := r.ParseMultipartForm(33_554_432); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("File must not be larger than %d bytes", maxUploadSize))
+		return
+	}
+
+	file, handler, err := r.FormFile("import_file")
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("import_file must be provided"))
+		return
+	}
+	defer file.Close()
+
+	// the files of the form are removed when the request ends,
+	// so the archive is copied for the background job
+	archive, err := os.CreateTemp("", "import-*.zip")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	archivePath := archive.Name()
+
+	_, err = io.Copy(archive, file)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archivePath)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	rows, err := readImportManifest(archivePath)
+	if err != nil {
+		os.Remove(archivePath)
+		app.failedValidationResponse(w, r, map[string]string{"import_file": err.Error()})
+		return
+	}
+
+	adminUserID := app.contextGetAdmin(r).ID
+
+	job := &data.ImportJob{
+		Status:      data.ImportPending,
+		Filename:    handler.Filename,
+		TotalRows:   int32(len(rows)),
+		AdminUserID: &adminUserID,
+	}
+
+	err = app.models.ImportJobs.Insert(job)
+	if err != nil {
+		os.Remove(archivePath)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the job is updated by the background job while the response is written
+	backgroundJob := *job
+
+	app.background(func() {
+		defer os.Remove(archivePath)
+
+		app.runImport(&backgroundJob, archivePath, rows)
+	})
+
+	// utility header
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/import/%d", job.ID))
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"import_job": job}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showImport(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readNamedIDParam(r, "job_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.ImportJobs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import_job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
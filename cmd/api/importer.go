@@ -0,0 +1,317 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+const (
+	importManifestName = "manifest.csv"
+	maxImportRows      = 1000
+	maxImportImageSize = 10_485_760 // 10MB, like the uploaded images
+	// the progress of the job is saved every importProgressRows rows
+	importProgressRows = 10
+)
+
+// A row of the manifest of an import. The tags column is optional, its
+// tags are separated by commas, e.g. "handmade, wool".
+type importRow struct {
+	line        int
+	name        string
+	description string
+	category    string
+	image       string
+	tags        []string
+}
+
+// Read the rows of the manifest of the archive, checking its columns
+func readImportManifest(archivePath string) ([]importRow, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, errors.New("must be a ZIP archive")
+	}
+	defer archive.Close()
+
+	manifest, err := archive.Open(importManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("must contain a %s file", importManifestName)
+	}
+	defer manifest.Close()
+
+	reader := csv.NewReader(manifest)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s must have a header row", importManifestName)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		// spreadsheets may add a byte order mark to the first column
+		column = strings.TrimPrefix(column, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range []string{"name", "description", "category", "image"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%s must have a %s column", importManifestName, column)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid CSV file: %v", importManifestName, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		rows = append(rows, importRow{
+			line:        line,
+			name:        field(record, "name"),
+			description: field(record, "description"),
+			category:    field(record, "category"),
+			image:       field(record, "image"),
+			tags:        splitImportTags(field(record, "tags")),
+		})
+
+		if len(rows) > maxImportRows {
+			return nil, fmt.Errorf("%s must not have more than %d rows", importManifestName, maxImportRows)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s must have at least 1 row", importManifestName)
+	}
+
+	return rows, nil
+}
+
+// Import the rows of the manifest, saving the progress and the per row
+// report in the job. A failing row does not stop the import of the others.
+func (app *application) runImport(job *data.ImportJob, archivePath string, rows []importRow) {
+	job.Status = data.ImportRunning
+	app.saveImportJob(job)
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		job.Status = data.ImportFailed
+		job.Error = err.Error()
+		app.saveImportJob(job)
+		return
+	}
+	defer archive.Close()
+
+	// categories found or created by the previous rows, by name
+	categories := make(map[string]*data.Category)
+
+	for i, row := range rows {
+		job.Report = append(job.Report, app.importRow(job, archive, row, categories))
+		job.ProcessedRows++
+
+		if (i+1)%importProgressRows == 0 {
+			app.saveImportJob(job)
+		}
+	}
+
+	job.Status = data.ImportFinished
+	app.saveImportJob(job)
+}
+
+// Create the item of a row of the manifest, with its image
+func (app *application) importRow(job *data.ImportJob, archive *zip.ReadCloser, row importRow, categories map[string]*data.Category) *data.ImportRowResult {
+	result := &data.ImportRowResult{Row: row.line, Status: "failed"}
+
+	fail := func(err error) *data.ImportRowResult {
+		app.logger.PrintError(err, map[string]string{
+			"import_job": fmt.Sprint(job.ID),
+			"row":        fmt.Sprint(row.line),
+		})
+		result.Errors = map[string]string{"row": "could not be imported"}
+		return result
+	}
+
+	v := validator.New()
+
+	item := &data.Item{
+		Name:         row.name,
+		Description:  row.description,
+		Currency:     data.DefaultCurrency,
+		Availability: data.DefaultAvailability,
+		Tags:         row.tags,
+	}
+
+	data.ValidateItem(v, item)
+
+	v.Check(row.category != "", "category", "must be provided")
+	v.Check(len(row.category) <= 100, "category", "must not be more than 100 bytes long")
+
+	var image []byte
+	if row.image != "" {
+		var err error
+		image, err = readImportImage(archive, row.image)
+		if err != nil {
+			v.AddError("image", err.Error())
+		}
+	}
+
+	// the category is only created for a valid row, so a rejected row
+	// does not leave a new category behind
+	if !v.Valid() {
+		result.Errors = v.Errors
+		return result
+	}
+
+	category, err := app.importCategory(job, row.category, categories)
+	if err != nil {
+		return fail(err)
+	}
+
+	item.CategoryID = category.ID
+
+	// the imported items have no attributes, this only fails for the
+	// required attributes of an existing category
+	if data.ValidateItemAttributes(v, item.Attributes, category.AttributeSchema); !v.Valid() {
+		result.Errors = v.Errors
+		return result
+	}
+
+	if image == nil {
+		err = app.models.Items.Insert(item, importAdminUserID(job))
+		if err != nil {
+			return fail(err)
+		}
+	} else {
+		info, err := app.s3Manager.UploadBuffer(image, row.image)
+		if err != nil {
+			return fail(err)
+		}
+
+		attachment := &data.ItemAttachment{
+			Key:         info.Key,
+			Filename:    info.Filename,
+			ContentType: info.ContentType,
+			ByteSize:    info.ByteSize,
+		}
+
+		// the item is not created without its image, nothing is left
+		// behind for a failed row
+		err = app.models.Items.InsertWithAttachment(item, attachment, importAdminUserID(job))
+		if err != nil {
+			app.s3Manager.DeleteFile(attachment.Key)
+			return fail(err)
+		}
+	}
+
+	result.Status = "created"
+	result.ItemID = item.ID
+
+	return result
+}
+
+// Return the category with the given name, creating it when it does not exist
+func (app *application) importCategory(job *data.ImportJob, name string, categories map[string]*data.Category) (*data.Category, error) {
+	key := strings.ToLower(name) // category names are case insensitive
+	if category, ok := categories[key]; ok {
+		return category, nil
+	}
+
+	category, err := app.models.Categories.GetByName(name)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		category = &data.Category{
+			Name:        name,
+			Description: fmt.Sprintf("Created by the import %d", job.ID),
+		}
+
+		err = app.models.Categories.Insert(category, importAdminUserID(job))
+		if errors.Is(err, data.ErrDuplicateName) {
+			// created by someone else since the lookup
+			category, err = app.models.Categories.GetByName(name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	categories[key] = category
+
+	return category, nil
+}
+
+// Return the tags of a tags cell of the manifest, skipping the empty ones
+func splitImportTags(cell string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(cell, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// Return the content of a JPEG or PNG image of the archive
+func readImportImage(archive *zip.ReadCloser, name string) ([]byte, error) {
+	file, err := archive.Open(path.Clean(name))
+	if err != nil {
+		return nil, errors.New("must be a file of the archive")
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(io.LimitReader(file, maxImportImageSize+1))
+	if err != nil {
+		return nil, errors.New("must be a readable file")
+	}
+
+	if len(image) > maxImportImageSize {
+		return nil, fmt.Errorf("must not be larger than %d bytes", maxImportImageSize)
+	}
+
+	// only JPEG OR PNG allowed
+	fileType := http.DetectContentType(image)
+	if fileType != "image/jpeg" && fileType != "image/png" {
+		return nil, errors.New("must be a JPEG or PNG image")
+	}
+
+	return image, nil
+}
+
+// Save the progress of the job, errors are only logged because the
+// import goes on
+func (app *application) saveImportJob(job *data.ImportJob) {
+	err := app.models.ImportJobs.Update(job)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"import_job": fmt.Sprint(job.ID)})
+	}
+}
+
+// Return the ID of the admin user who started the import, 0 when unknown
+func importAdminUserID(job *data.ImportJob) int64 {
+	if job.AdminUserID == nil {
+		return 0
+	}
+
+	return *job.AdminUserID
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+func (app *application) createItemVariant(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// the variant must belong to an existing item
+	_, err = app.models.Items.Get(itemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		SKU              string `json:"sku"`
+		Size             string `json:"size"`
+		Color            string `json:"color"`
+		Material         string `json:"material"`
+		Price            *int64 `json:"price"`
+		Stock            int32  `json:"stock"`
+		ItemAttachmentID *int64 `json:"item_attachment_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	variant := &data.ItemVariant{
+		ItemID:           itemID,
+		SKU:              input.SKU,
+		Size:             input.Size,
+		Color:            input.Color,
+		Material:         input.Material,
+		Price:            input.Price,
+		Stock:            input.Stock,
+		ItemAttachmentID: input.ItemAttachmentID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateItemVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ItemVariants.Insert(variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "a variant with this sku already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInvalidAttachment):
+			v.AddError("item_attachment_id", "must be an attachment of the item")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// utility header
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/items/%d/variants/%d", itemID, variant.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"variant": variant}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showItemVariant(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variant, err := app.models.ItemVariants.Get(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateItemVariant(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variant, err := app.models.ItemVariants.Get(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// we use pointers here for support partial update, the nullable fields
+	// are kept raw to tell a missing field from a null that clears it
+	var input struct {
+		SKU              *string         `json:"sku"`
+		Size             *string         `json:"size"`
+		Color            *string         `json:"color"`
+		Material         *string         `json:"material"`
+		Price            json.RawMessage `json:"price"`
+		Stock            *int32          `json:"stock"`
+		ItemAttachmentID json.RawMessage `json:"item_attachment_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.SKU != nil {
+		variant.SKU = *input.SKU
+	}
+	if input.Size != nil {
+		variant.Size = *input.Size
+	}
+	if input.Color != nil {
+		variant.Color = *input.Color
+	}
+	if input.Material != nil {
+		variant.Material = *input.Material
+	}
+	if input.Stock != nil {
+		variant.Stock = *input.Stock
+	}
+
+	err = readNullableInt64(input.Price, "price", &variant.Price)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = readNullableInt64(input.ItemAttachmentID, "item_attachment_id", &variant.ItemAttachmentID)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateItemVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ItemVariants.Update(variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "a variant with this sku already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInvalidAttachment):
+			v.AddError("item_attachment_id", "must be an attachment of the item")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteItemVariant(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	id, err := app.readNamedIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.ItemVariants.Delete(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "variant successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listItemVariants(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// the item already embeds its variants
+	item, err := app.models.Items.Get(itemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variants": item.Variants}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Set dst from the raw value of a nullable field of a partial update:
+// a missing field keeps dst, null clears it and a number replaces it.
+func readNullableInt64(raw json.RawMessage, field string, dst **int64) error {
+	switch {
+	case raw == nil:
+		return nil
+	case bytes.Equal(raw, []byte("null")):
+		*dst = nil
+		return nil
+	}
+
+	var value int64
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return fmt.Errorf("body contains incorrect JSON type for field %q", field)
+	}
+	*dst = &value
+
+	return nil
+}
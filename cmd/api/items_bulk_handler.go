@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Apply an action to many items at once. The items are given as a list of
+// IDs with optional versions, or as a filter with the query string syntax
+// of the items listing, e.g. "category_id=3&availability=sold".
+// Served at /v1/items_bulk because httprouter does not allow /v1/items/bulk
+// next to /v1/items/:id.
+func (app *application) bulkItems(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.BulkItemAction
+		Items  []data.BulkItemTarget `json:"items"`
+		Filter *string               `json:"filter"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateBulkItemAction(v, input.BulkItemAction)
+
+	var search *data.ItemSearch
+
+	switch {
+	case input.Filter != nil && input.Items != nil:
+		v.AddError("filter", "must not be provided along with items")
+	case input.Filter != nil:
+		qs, err := url.ParseQuery(*input.Filter)
+		if err != nil {
+			v.AddError("filter", "must be a valid query string")
+			break
+		}
+
+		itemSearch := app.readItemSearch(qs, v)
+		search = &itemSearch
+	case len(input.Items) == 0:
+		v.AddError("items", "must contain at least 1 item")
+	default:
+		data.ValidateBulkItemTargets(v, input.Items)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Items.Bulk(input.BulkItemAction, input.Items, search, app.contextGetAdmin(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidBulkTarget):
+			v.AddError("category_id", "must be an existing category")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrTooManyBulkItems):
+			v.AddError("filter", fmt.Sprintf("must not match more than %d items", data.MaxBulkItems))
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.bulkItemsFailedResponse(w, r, http.StatusConflict, results)
+		case errors.Is(err, data.ErrBulkItemsFailed):
+			app.bulkItemsFailedResponse(w, r, http.StatusUnprocessableEntity, results)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Send the per-item results of a bulk operation that was not applied
+func (app *application) bulkItemsFailedResponse(w http.ResponseWriter, r *http.Request, status int, results []*data.BulkItemResult) {
+	env := envelope{
+		"error":   "the operation was not applied, no item was modified",
+		"results": results,
+	}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
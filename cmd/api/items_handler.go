@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/jesusangelm/api_galeria/internal/data"
@@ -14,9 +16,15 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	// declare a struct to hold the information we expect to receive
 	// this struct will be the target decode destination
 	var input struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		CategoryID  int64  `json:"category_id"`
+		Name         string         `json:"name"`
+		Description  string         `json:"description"`
+		CategoryID   int64          `json:"category_id"`
+		Price        int64          `json:"price"`
+		Currency     string         `json:"currency"`
+		Availability string         `json:"availability"`
+		Stock        int32          `json:"stock"`
+		Attributes   map[string]any `json:"attributes"`
+		Tags         []string       `json:"tags"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -26,9 +34,22 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	item := &data.Item{
-		Name:        input.Name,
-		Description: input.Description,
-		CategoryID:  input.CategoryID,
+		Name:         input.Name,
+		Description:  input.Description,
+		CategoryID:   input.CategoryID,
+		Price:        input.Price,
+		Currency:     input.Currency,
+		Availability: input.Availability,
+		Stock:        input.Stock,
+		Attributes:   input.Attributes,
+		Tags:         input.Tags,
+	}
+
+	if item.Currency == "" {
+		item.Currency = data.DefaultCurrency
+	}
+	if item.Availability == "" {
+		item.Availability = data.DefaultAvailability
 	}
 
 	v := validator.New()
@@ -36,12 +57,18 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	data.ValidateItem(v, item)
 	data.ValidateItemCategoryID(v, item)
 
+	err = app.validateItemAttributes(v, item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Items.Insert(item)
+	err = app.models.Items.Insert(item, app.contextGetAdmin(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -87,23 +114,43 @@ func (app *application) multipartCreateItem(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
-	item := &data.Item{
-		Name:        r.FormValue("name"),
-		Description: r.FormValue("description"),
-		CategoryID:  int64(categoryID),
-	}
 
 	v := validator.New()
 
+	item := &data.Item{
+		Name:         r.FormValue("name"),
+		Description:  r.FormValue("description"),
+		CategoryID:   int64(categoryID),
+		Price:        int64(app.readInt(r.PostForm, "price", 0, v)),
+		Currency:     app.readString(r.PostForm, "currency", data.DefaultCurrency),
+		Availability: app.readString(r.PostForm, "availability", data.DefaultAvailability),
+		Stock:        int32(app.readInt(r.PostForm, "stock", 0, v)),
+		Tags:         app.readCSV(r.PostForm, "tags", nil), // e.g. tags=handmade,wool
+	}
+
+	// attributes are sent as a JSON object in a form field
+	if attributes := r.FormValue("attributes"); attributes != "" {
+		err = json.Unmarshal([]byte(attributes), &item.Attributes)
+		if err != nil {
+			v.AddError("attributes", "must be a JSON object")
+		}
+	}
+
 	data.ValidateItem(v, item)
 	data.ValidateItemCategoryID(v, item)
 
+	err = app.validateItemAttributes(v, item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Items.Insert(item)
+	err = app.models.Items.Insert(item, app.contextGetAdmin(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -148,7 +195,7 @@ func (app *application) showItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := app.models.Items.Get(id)
+	item, err := app.models.Items.GetLocalized(id, app.readLocale(r))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -159,6 +206,9 @@ func (app *application) showItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the content depends on the requested locale
+	w.Header().Add("Vary", "Accept-Language")
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -185,9 +235,15 @@ func (app *application) updateItem(w http.ResponseWriter, r *http.Request) {
 
 	// we use pointers here for support partial update
 	var input struct {
-		Name        *string `json:"name"`
-		Description *string `json:"description"`
-		CategoryID  *int64  `json:"category_id"`
+		Name         *string        `json:"name"`
+		Description  *string        `json:"description"`
+		CategoryID   *int64         `json:"category_id"`
+		Price        *int64         `json:"price"`
+		Currency     *string        `json:"currency"`
+		Availability *string        `json:"availability"`
+		Stock        *int32         `json:"stock"`
+		Attributes   map[string]any `json:"attributes"`
+		Tags         []string       `json:"tags"` // replaces all the tags, [] removes them
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -205,15 +261,41 @@ func (app *application) updateItem(w http.ResponseWriter, r *http.Request) {
 	if input.CategoryID != nil {
 		item.CategoryID = *input.CategoryID
 	}
+	if input.Price != nil {
+		item.Price = *input.Price
+	}
+	if input.Currency != nil {
+		item.Currency = *input.Currency
+	}
+	if input.Availability != nil {
+		item.Availability = *input.Availability
+	}
+	if input.Stock != nil {
+		item.Stock = *input.Stock
+	}
+	if input.Attributes != nil {
+		item.Attributes = input.Attributes
+	}
+	if input.Tags != nil {
+		item.Tags = input.Tags
+	}
 
 	v := validator.New()
 
-	if data.ValidateItem(v, item); !v.Valid() {
+	data.ValidateItem(v, item)
+
+	err = app.validateItemAttributes(v, item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Items.Update(item)
+	err = app.models.Items.Update(item, app.contextGetAdmin(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -248,7 +330,7 @@ func (app *application) deleteItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item successfully deleted"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item successfully moved to the trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -256,21 +338,24 @@ func (app *application) deleteItem(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) listItems(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name       string
-		CategoryID int
+		data.ItemSearch
+		Facets bool
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query() // To get filter parameters from the QueryString
 
-	input.Name = app.readString(qs, "name", "")
-	input.CategoryID = app.readInt(qs, "category_id", 0, v)
+	input.ItemSearch = app.readItemSearch(qs, v)
+	input.Locale = app.readLocale(r)
+	input.Facets = app.readBool(qs, "facets", false, v)
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
 	input.Filters.SortSafeList = []string{
-		"id", "name", "created_at", "-id", "-name", "-created_at",
+		"id", "name", "created_at", "price", "position",
+		"-id", "-name", "-created_at", "-price", "-position", "relevance",
 	}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
@@ -278,13 +363,66 @@ func (app *application) listItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	items, metadata, err := app.models.Items.List(input.Name, input.CategoryID, input.Filters)
+	items, metadata, err := app.models.Items.List(input.ItemSearch, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+	env := envelope{"items": items, "metadata": metadata}
+
+	// facets are optional because they need an extra query
+	if input.Facets {
+		facets, err := app.models.Items.Facets(input.ItemSearch)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	// the content depends on the requested locale
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Read the filters of the items listing from the query string
+func (app *application) readItemSearch(qs url.Values, v *validator.Validator) data.ItemSearch {
+	search := data.ItemSearch{
+		Name:         app.readString(qs, "name", ""),
+		CategoryID:   app.readInt(qs, "category_id", 0, v),
+		CollectionID: app.readInt(qs, "collection_id", 0, v),
+		MinPrice:     int64(app.readInt(qs, "min_price", 0, v)),
+		MaxPrice:     int64(app.readInt(qs, "max_price", 0, v)),
+		Availability: app.readString(qs, "availability", ""),
+		Attributes:   app.readPrefixedMap(qs, "attr."),
+		Tags:         app.readCSV(qs, "tags", nil),
+	}
+
+	data.ValidateItemSearch(v, search)
+
+	return search
+}
+
+// Validate the attributes of the item against the attribute schema of its
+// category. Errors are added to the validator, only unexpected errors are returned.
+func (app *application) validateItemAttributes(v *validator.Validator, item *data.Item) error {
+	schema, err := app.models.Categories.GetAttributeSchema(item.CategoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category_id", "must be an existing category")
+			return nil
+		default:
+			return err
+		}
+	}
+
+	data.ValidateItemAttributes(v, item.Attributes, schema)
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"github.com/jesusangelm/api_galeria/internal/data"
 	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
 	"github.com/jesusangelm/api_galeria/internal/jsonlog"
+	"github.com/jesusangelm/api_galeria/internal/mailer"
 	"github.com/jesusangelm/api_galeria/internal/vcs"
 )
 
@@ -30,13 +32,7 @@ type config struct {
 		maxIdleConns int
 		maxIdleTime  string
 	}
-	s3 struct {
-		bucket            string
-		region            string
-		endpoint          string
-		access_key_id     string
-		secret_access_key string
-	}
+	s3      s3Config
 	limiter struct {
 		rps     float64
 		burst   int
@@ -45,6 +41,26 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	trash struct {
+		retentionDays int
+	}
+	// without an SMTP host the emails are written to mail.file
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
+	mail struct {
+		file string
+	}
+	// destination of the migrate-storage command
+	storageMigration struct {
+		dest    s3Config
+		workers int
+		envFile string
+	}
 	auth         Auth
 	JWTSecret    string
 	JWTIssuer    string
@@ -53,17 +69,34 @@ type config struct {
 	Domain       string
 }
 
+type s3Config struct {
+	bucket            string
+	region            string
+	endpoint          string
+	access_key_id     string
+	secret_access_key string
+}
+
 type application struct {
 	config    config
 	logger    *jsonlog.Logger
 	models    data.Models
 	s3Manager filestorage.S3
+	mailer    mailer.Mailer
 	wg        sync.WaitGroup
 }
 
 func main() {
 	var cfg config
 
+	// The first argument may be a command, followed by the flags:
+	// api [flags], api backup [flags], api restore [flags] or api migrate-storage [flags]
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
 	// Base app config
 	flag.IntVar(&cfg.port, "port", 4000, "API Server Port to listen")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
@@ -80,6 +113,15 @@ func main() {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
 	})
+	// Trash config
+	flag.IntVar(&cfg.trash.retentionDays, "trash-retention-days", 30, "Days before the trashed items and categories are purged (0 disables the purge)")
+	// SMTP config
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "", "SMTP host (empty writes the emails to -mail-file)")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 587, "SMTP port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Galeria <no-reply@ejemplo.com>", "SMTP sender")
+	flag.StringVar(&cfg.mail.file, "mail-file", "", "File where the emails are written when there is no SMTP host (empty writes them to stdout in development)")
 	// S3 Config
 	// API key requires delete file from bucket permission
 	flag.StringVar(&cfg.s3.bucket, "s3_bucket", "bucket", "S3 Bucket Name")
@@ -88,6 +130,15 @@ func main() {
 	flag.StringVar(&cfg.s3.access_key_id, "s3_akid", "", "S3 Access Key ID")
 	flag.StringVar(&cfg.s3.secret_access_key, "s3_sak", "", "S3 Secret Access Key")
 
+	// Storage migration config, the source is the S3 config above
+	flag.StringVar(&cfg.storageMigration.dest.bucket, "dest-s3_bucket", "", "Destination S3 Bucket Name")
+	flag.StringVar(&cfg.storageMigration.dest.region, "dest-s3_region", "", "Destination S3 Region")
+	flag.StringVar(&cfg.storageMigration.dest.endpoint, "dest-s3_endpoint", "", "Destination S3 Endpoint")
+	flag.StringVar(&cfg.storageMigration.dest.access_key_id, "dest-s3_akid", "", "Destination S3 Access Key ID")
+	flag.StringVar(&cfg.storageMigration.dest.secret_access_key, "dest-s3_sak", "", "Destination S3 Secret Access Key")
+	flag.IntVar(&cfg.storageMigration.workers, "migrate-workers", 4, "Files copied at the same time by the migrate-storage command")
+	flag.StringVar(&cfg.storageMigration.envFile, "migrate-env-file", "", "Env file (e.g. .envrc) switched to the destination S3 config after a successful migration")
+
 	// JWT Auth settings
 	flag.StringVar(&cfg.JWTSecret, "jwt-secret", "a_secret", "JWT Signing Secret")
 	flag.StringVar(&cfg.JWTIssuer, "jwt-issuer", "ejemplo.com", "JWT Signing Issuer")
@@ -95,10 +146,13 @@ func main() {
 	flag.StringVar(&cfg.CookieDomain, "cookie-domain", "localhost", "Cookie domain")
 	flag.StringVar(&cfg.Domain, "domain", "ejemplo.com", "Domain")
 
+	// Backup and restore commands config
+	backupFile := flag.String("backup-file", "backup.zip", "Archive written by the backup command and read by the restore command")
+
 	// Create a new version boolean flag with the default value of false.
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
-	flag.Parse()
+	flag.CommandLine.Parse(args)
 
 	// If the version flag value is true, then print out the version number and
 	// immediately exit.
@@ -107,6 +161,11 @@ func main() {
 		os.Exit(0)
 	}
 
+	if command != "serve" && command != "backup" && command != "restore" && command != "migrate-storage" {
+		fmt.Fprintf(os.Stderr, "unknown command %q, use backup, restore, migrate-storage or no command to start the server\n", command)
+		os.Exit(2)
+	}
+
 	cfg.auth = Auth{
 		Issuer:        cfg.JWTIssuer,
 		Audience:      cfg.JWTAudience,
@@ -130,13 +189,32 @@ func main() {
 	defer dbConn.Close()
 	logger.PrintInfo("database connection pool established", nil)
 
-	s3Session, err := createS3Session(cfg)
+	s3Session, err := createS3Session(cfg.s3)
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 
 	s3Manager := filestorage.NewS3Manager(s3Session, cfg.s3.bucket)
 
+	var appMailer mailer.Mailer
+	switch {
+	case cfg.smtp.host != "":
+		appMailer = mailer.NewSMTP(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender)
+	case cfg.mail.file != "":
+		mailFile, err := os.OpenFile(cfg.mail.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		defer mailFile.Close()
+
+		appMailer = mailer.NewFileSink(mailFile)
+	case cfg.env == "development":
+		appMailer = mailer.NewFileSink(os.Stdout)
+	case command == "serve":
+		// the emails contain password reset tokens, they must not end in the logs
+		logger.PrintFatal(errors.New("-smtp-host or -mail-file must be provided outside of development"), nil)
+	}
+
 	// Initialize the application struct
 	// for application config
 	app := application{
@@ -144,10 +222,20 @@ func main() {
 		logger:    logger,
 		models:    data.NewModels(dbConn, s3Manager),
 		s3Manager: s3Manager,
+		mailer:    appMailer,
 	}
 
-	// call app.serve() to start the server
-	err = app.serve()
+	switch command {
+	case "backup":
+		err = app.backup(*backupFile)
+	case "restore":
+		err = app.restore(*backupFile)
+	case "migrate-storage":
+		err = app.migrateStorage()
+	default:
+		// call app.serve() to start the server
+		err = app.serve()
+	}
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
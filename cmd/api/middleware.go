@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/justinas/alice"
 	"golang.org/x/time/rate"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
 )
 
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -114,11 +119,70 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 
 func (app *application) authRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _, err := app.config.auth.GetTokenFromHeaderAndVerify(w, r)
+		_, claims, err := app.config.auth.GetTokenFromHeaderAndVerify(w, r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// the subject of the token is the ID of the admin user
+		adminUserID, err := strconv.ParseInt(claims.Subject, 10, 64)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+
+		// the admin user is checked on every request, so deleting or
+		// deactivating it takes effect before its tokens expire
+		adminUser, err := app.models.AdminUser.GetById(adminUserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if adminUser.Deactivated() {
+			app.deactivatedAccountResponse(w, r)
+			return
+		}
+
+		if !adminUser.Activated {
+			app.inactiveAccountResponse(w, r)
+			return
+		}
+
+		// the role is carried in the token, a token issued before a role
+		// change must be refreshed to get the new role
+		if claims.Role != adminUser.Role {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		// the handlers know who is acting with contextGetAdmin
+		r = app.contextSetAdmin(r, adminUser)
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Reject the requests of the admin users whose role does not grant the
+// permission, it must be used after authRequired, e.g.
+// dynamic.Append(app.requirePermission("items:write"))
+func (app *application) requirePermission(code string) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			permissions := data.RolePermissions[app.contextGetAdmin(r).Role]
+
+			if !permissions.Include(code) {
+				app.notPermittedResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
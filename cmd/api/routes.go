@@ -19,25 +19,84 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPost, "/v1/authenticate", app.authenticate)
 	router.HandlerFunc(http.MethodGet, "/v1/refresh", app.refreshToken)
 	router.HandlerFunc(http.MethodGet, "/v1/logout", app.logout)
+	router.HandlerFunc(http.MethodPut, "/v1/admin_users/activated", app.activateAdminUser)
+	router.HandlerFunc(http.MethodPut, "/v1/admin_users/password", app.resetAdminUserPassword)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetToken)
+	router.HandlerFunc(http.MethodGet, "/v1/search/suggest", app.searchSuggest)
+	router.HandlerFunc(http.MethodGet, "/v1/public/home", app.showHome)
 
 	// Dynamic middleware managed by alice with some custom middlewares
 	dynamic := alice.New(app.authRequired) // Auth and similars middleware here
 
+	// The authenticated routes also require a permission of the role of the admin user
+	itemsRead := dynamic.Append(app.requirePermission("items:read"))
+	itemsWrite := dynamic.Append(app.requirePermission("items:write"))
+	categoriesRead := dynamic.Append(app.requirePermission("categories:read"))
+	categoriesWrite := dynamic.Append(app.requirePermission("categories:write"))
+	collectionsRead := dynamic.Append(app.requirePermission("collections:read"))
+	collectionsWrite := dynamic.Append(app.requirePermission("collections:write"))
+	adminUsersRead := dynamic.Append(app.requirePermission("admin_users:read"))
+	adminUsersWrite := dynamic.Append(app.requirePermission("admin_users:write"))
+
 	// AdminUsers routes
-	router.Handler(http.MethodPost, "/v1/admin_users", dynamic.ThenFunc(app.createAdminUser))
+	router.Handler(http.MethodGet, "/v1/admin_users", adminUsersRead.ThenFunc(app.listAdminUsers))
+	router.Handler(http.MethodPost, "/v1/admin_users", adminUsersWrite.ThenFunc(app.createAdminUser))
+	router.Handler(http.MethodGet, "/v1/admin_users/:id", adminUsersRead.ThenFunc(app.showAdminUser))
+	router.Handler(http.MethodPatch, "/v1/admin_users/:id", adminUsersWrite.ThenFunc(app.updateAdminUser))
+	router.Handler(http.MethodDelete, "/v1/admin_users/:id", adminUsersWrite.ThenFunc(app.deleteAdminUser))
+	router.Handler(http.MethodPatch, "/v1/admin_users/:id/password", dynamic.ThenFunc(app.updateAdminUserPassword))
+	// Tokens routes
+	router.Handler(http.MethodPost, "/v1/tokens/activation", adminUsersWrite.ThenFunc(app.createActivationToken))
 	// Categories routes
-	router.Handler(http.MethodGet, "/v1/categories", dynamic.ThenFunc(app.listCategories))
-	router.Handler(http.MethodPost, "/v1/categories", dynamic.ThenFunc(app.createCategory))
-	router.Handler(http.MethodGet, "/v1/categories/:id", dynamic.ThenFunc(app.showCategory))
-	router.Handler(http.MethodPatch, "/v1/categories/:id", dynamic.ThenFunc(app.updateCategory))
-	router.Handler(http.MethodDelete, "/v1/categories/:id", dynamic.ThenFunc(app.deleteCategory))
+	router.Handler(http.MethodGet, "/v1/categories", categoriesRead.ThenFunc(app.listCategories))
+	router.Handler(http.MethodPost, "/v1/categories", categoriesWrite.ThenFunc(app.createCategory))
+	router.Handler(http.MethodGet, "/v1/categories/:id", categoriesRead.ThenFunc(app.showCategory))
+	router.Handler(http.MethodPatch, "/v1/categories/:id", categoriesWrite.ThenFunc(app.updateCategory))
+	router.Handler(http.MethodDelete, "/v1/categories/:id", categoriesWrite.ThenFunc(app.deleteCategory))
+	router.Handler(http.MethodPost, "/v1/categories/:id/restore", categoriesWrite.ThenFunc(app.restoreCategory))
+	router.Handler(http.MethodGet, "/v1/categories/:id/archive", categoriesRead.ThenFunc(app.showCategoryArchive))
+	router.Handler(http.MethodPost, "/v1/categories/:id/cover", categoriesWrite.ThenFunc(app.uploadCategoryCover))
+	router.Handler(http.MethodPut, "/v1/categories/:id/cover", categoriesWrite.ThenFunc(app.setCategoryCover))
+	router.Handler(http.MethodDelete, "/v1/categories/:id/cover", categoriesWrite.ThenFunc(app.deleteCategoryCover))
+	router.Handler(http.MethodPut, "/v1/categories/:id/items/order", categoriesWrite.ThenFunc(app.reorderCategoryItems))
+	router.Handler(http.MethodGet, "/v1/categories/:id/translations", categoriesRead.ThenFunc(app.listCategoryTranslations))
+	router.Handler(http.MethodPut, "/v1/categories/:id/translations/:locale", categoriesWrite.ThenFunc(app.setCategoryTranslation))
+	router.Handler(http.MethodDelete, "/v1/categories/:id/translations/:locale", categoriesWrite.ThenFunc(app.deleteCategoryTranslation))
+	// Collections routes
+	router.Handler(http.MethodGet, "/v1/collections", collectionsRead.ThenFunc(app.listCollections))
+	router.Handler(http.MethodPost, "/v1/collections", collectionsWrite.ThenFunc(app.createCollection))
+	router.Handler(http.MethodGet, "/v1/collections/:id", collectionsRead.ThenFunc(app.showCollection))
+	router.Handler(http.MethodPatch, "/v1/collections/:id", collectionsWrite.ThenFunc(app.updateCollection))
+	router.Handler(http.MethodDelete, "/v1/collections/:id", collectionsWrite.ThenFunc(app.deleteCollection))
+	// Trash routes
+	router.Handler(http.MethodGet, "/v1/trash", itemsRead.ThenFunc(app.listTrash))
+	// Export routes
+	router.Handler(http.MethodGet, "/v1/export/items", itemsRead.ThenFunc(app.exportItems))
+	// Import routes
+	router.Handler(http.MethodPost, "/v1/import", itemsWrite.ThenFunc(app.createImport))
+	router.Handler(http.MethodGet, "/v1/import/:job_id", itemsRead.ThenFunc(app.showImport))
 	// Items routes
-	router.Handler(http.MethodGet, "/v1/items", dynamic.ThenFunc(app.listItems))
-	router.Handler(http.MethodPost, "/v1/items", dynamic.ThenFunc(app.createItem))
-	router.Handler(http.MethodPost, "/v1/items_multipart", dynamic.ThenFunc(app.multipartCreateItem))
-	router.Handler(http.MethodGet, "/v1/items/:id", dynamic.ThenFunc(app.showItem))
-	router.Handler(http.MethodPatch, "/v1/items/:id", dynamic.ThenFunc(app.updateItem))
-	router.Handler(http.MethodDelete, "/v1/items/:id", dynamic.ThenFunc(app.deleteItem))
+	router.Handler(http.MethodGet, "/v1/items", itemsRead.ThenFunc(app.listItems))
+	router.Handler(http.MethodPost, "/v1/items", itemsWrite.ThenFunc(app.createItem))
+	router.Handler(http.MethodPost, "/v1/items_bulk", itemsWrite.ThenFunc(app.bulkItems))
+	router.Handler(http.MethodPost, "/v1/items_multipart", itemsWrite.ThenFunc(app.multipartCreateItem))
+	router.Handler(http.MethodGet, "/v1/items/:id", itemsRead.ThenFunc(app.showItem))
+	router.Handler(http.MethodPatch, "/v1/items/:id", itemsWrite.ThenFunc(app.updateItem))
+	router.Handler(http.MethodDelete, "/v1/items/:id", itemsWrite.ThenFunc(app.deleteItem))
+	router.Handler(http.MethodPost, "/v1/items/:id/restore", itemsWrite.ThenFunc(app.restoreItem))
+	router.Handler(http.MethodPut, "/v1/items/:id/featured", itemsWrite.ThenFunc(app.featureItem))
+	router.Handler(http.MethodDelete, "/v1/items/:id/featured", itemsWrite.ThenFunc(app.unfeatureItem))
+	router.Handler(http.MethodGet, "/v1/items/:id/revisions", itemsRead.ThenFunc(app.listItemRevisions))
+	router.Handler(http.MethodPost, "/v1/items/:id/revisions/:version/restore", itemsWrite.ThenFunc(app.restoreItemRevision))
+	router.Handler(http.MethodGet, "/v1/items/:id/translations", itemsRead.ThenFunc(app.listItemTranslations))
+	router.Handler(http.MethodPut, "/v1/items/:id/translations/:locale", itemsWrite.ThenFunc(app.setItemTranslation))
+	router.Handler(http.MethodDelete, "/v1/items/:id/translations/:locale", itemsWrite.ThenFunc(app.deleteItemTranslation))
+	// Item variants routes
+	router.Handler(http.MethodGet, "/v1/items/:id/variants", itemsRead.ThenFunc(app.listItemVariants))
+	router.Handler(http.MethodPost, "/v1/items/:id/variants", itemsWrite.ThenFunc(app.createItemVariant))
+	router.Handler(http.MethodGet, "/v1/items/:id/variants/:variant_id", itemsRead.ThenFunc(app.showItemVariant))
+	router.Handler(http.MethodPatch, "/v1/items/:id/variants/:variant_id", itemsWrite.ThenFunc(app.updateItemVariant))
+	router.Handler(http.MethodDelete, "/v1/items/:id/variants/:variant_id", itemsWrite.ThenFunc(app.deleteItemVariant))
 
 	// Standard middleware managed by alice with some custom middlewares
 	standard := alice.New(app.recoverPanic, app.enableCORS, app.rateLimit)
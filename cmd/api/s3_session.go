@@ -6,13 +6,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
-func createS3Session(cfg config) (*session.Session, error) {
+func createS3Session(cfg s3Config) (*session.Session, error) {
 	session, err := session.NewSession(&aws.Config{
-		Region:   aws.String(cfg.s3.region),
-		Endpoint: aws.String(cfg.s3.endpoint),
+		Region:   aws.String(cfg.region),
+		Endpoint: aws.String(cfg.endpoint),
 		Credentials: credentials.NewStaticCredentials(
-			cfg.s3.access_key_id,
-			cfg.s3.secret_access_key,
+			cfg.access_key_id,
+			cfg.secret_access_key,
 			"",
 		),
 	})
@@ -22,10 +22,15 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	// the trash purge stops when the server starts shutting down
+	stopPurge := make(chan struct{})
+	go app.purgeTrash(stopPurge)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		s := <-quit
+		close(stopPurge)
 
 		app.logger.PrintInfo("shutting down server", map[string]string{
 			"signal": s.String(),
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+)
+
+// Time limit for copying a single file
+const storageMigrationFileTimeout = 5 * time.Minute
+
+// Copy every file referenced by the database from the configured bucket to the
+// destination bucket. The copies are verified with their SHA-256 checksum,
+// which is kept in the metadata of the copy, so an interrupted migration can be
+// run again and skips the files already copied. When every file is copied and
+// an env file is given, its S3 variables are switched to the destination.
+func (app *application) migrateStorage() error {
+	cfg := app.config.storageMigration
+
+	if cfg.dest.bucket == "" {
+		return errors.New("the destination bucket must be provided with -dest-s3_bucket")
+	}
+	if cfg.workers < 1 {
+		return errors.New("-migrate-workers must be at least 1")
+	}
+
+	destSession, err := createS3Session(cfg.dest)
+	if err != nil {
+		return err
+	}
+	dest := filestorage.NewS3Manager(destSession, cfg.dest.bucket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	keys, err := app.models.Backup.StorageKeys(ctx, app.models.Backup.DB)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("migrating storage", map[string]string{
+		"files": fmt.Sprint(len(keys)),
+		"from":  app.config.s3.bucket,
+		"to":    cfg.dest.bucket,
+	})
+
+	queue := make(chan string)
+
+	var mu sync.Mutex
+	copied, skipped, failed := 0, 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for key := range queue {
+				done, err := app.migrateFile(&dest, key)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					failed++
+					app.logger.PrintError(err, map[string]string{"key": key})
+				case done:
+					copied++
+				default:
+					skipped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		queue <- key
+	}
+	close(queue)
+
+	wg.Wait()
+
+	app.logger.PrintInfo("storage migrated", map[string]string{
+		"copied":  fmt.Sprint(copied),
+		"skipped": fmt.Sprint(skipped),
+		"failed":  fmt.Sprint(failed),
+	})
+
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be copied, run the command again to retry them", failed)
+	}
+
+	if cfg.envFile != "" {
+		err = switchEnvFileStorage(cfg.envFile, cfg.dest)
+		if err != nil {
+			return err
+		}
+
+		app.logger.PrintInfo("env file switched to the destination bucket", map[string]string{"file": cfg.envFile})
+	}
+
+	return nil
+}
+
+// Copy the file to the destination and verify the copy. Return false when
+// the file was already copied by a previous run.
+func (app *application) migrateFile(dest *filestorage.S3, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storageMigrationFileTimeout)
+	defer cancel()
+
+	source, err := app.s3Manager.HeadFile(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	// the checksum is only saved on the files copied by this command, nil
+	// when the file is not copied yet
+	existing, err := dest.HeadFile(ctx, key)
+	switch {
+	case errors.Is(err, filestorage.ErrFileNotFound):
+		existing = nil
+	case err != nil:
+		return false, err
+	}
+
+	// the source has a checksum when it was also uploaded with
+	// PutFileVerified, the download is not needed to compare them
+	if existing != nil && existing.SHA256 != "" && existing.SHA256 == source.SHA256 && existing.Size == source.Size {
+		return false, nil
+	}
+
+	file, err := app.s3Manager.GetFile(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(content)
+
+	// copied by a previous run, with the same content as the source
+	if existing != nil && existing.SHA256 == hex.EncodeToString(sum[:]) && existing.Size == int64(len(content)) {
+		return false, nil
+	}
+
+	err = dest.PutFileVerified(ctx, key, content, source.ContentType)
+	if err != nil {
+		return false, err
+	}
+
+	// read the copy back to compare it with the original
+	destFile, err := dest.GetFile(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer destFile.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, destFile)
+	if err != nil {
+		return false, err
+	}
+
+	if !bytes.Equal(hash.Sum(nil), sum[:]) {
+		// remove the bad copy, so the next run copies the file again
+		dest.DeleteFile(key)
+		return false, fmt.Errorf("checksum mismatch for the copy of %s", key)
+	}
+
+	return true, nil
+}
+
+// Set the S3 variables of the env file, like the .envrc used by the Makefile,
+// to the given config
+func switchEnvFileStorage(path string, cfg s3Config) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{
+		"S3_BUCKET":            cfg.bucket,
+		"S3_REGION":            cfg.region,
+		"S3_ENDPOINT":          cfg.endpoint,
+		"S3_ACCESS_KEY_ID":     cfg.access_key_id,
+		"S3_SECRET_ACCESS_KEY": cfg.secret_access_key,
+	}
+
+	env := string(content)
+	for name, value := range values {
+		// e.g. "export S3_BUCKET=galeria" or "S3_BUCKET='galeria'"
+		rx := regexp.MustCompile(`(?m)^((?:export\s+)?` + name + `=).*$`)
+		if !rx.MatchString(env) {
+			return fmt.Errorf("%s has no %s variable", path, name)
+		}
+
+		// single quoted, so the shell does not expand the value
+		quoted := "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+
+		env = rx.ReplaceAllStringFunc(env, func(line string) string {
+			return rx.FindStringSubmatch(line)[1] + quoted
+		})
+	}
+
+	return os.WriteFile(path, []byte(env), info.Mode().Perm())
+}
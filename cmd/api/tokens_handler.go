@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Time an activation token can be used
+const activationTokenTTL = 3 * 24 * time.Hour
+
+// Time a password reset token can be used
+const passwordResetTokenTTL = 45 * time.Minute
+
+// Create a new activation token for an admin user not activated yet, e.g.
+// when the token returned on its creation expired
+func (app *application) createActivationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching admin user found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if adminUser.Activated {
+		v.AddError("email", "admin user has already been activated")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Tokens.New(adminUser.ID, activationTokenTTL, data.ScopeActivation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"activation_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Email a password reset token to an active admin user. The response is the
+// same when there is no such admin user, so the emails can not be guessed.
+func (app *application) createPasswordResetToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	adminUser, err := app.models.AdminUser.GetByEmail(input.Email)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if adminUser != nil && adminUser.Activated && !adminUser.Deactivated() {
+		token, err := app.models.Tokens.New(adminUser.ID, passwordResetTokenTTL, data.ScopePasswordReset)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		emailData := map[string]any{
+			"FirstName":  adminUser.FirstName,
+			"Token":      token.Plaintext,
+			"TTLMinutes": int(passwordResetTokenTTL.Minutes()),
+		}
+		locale := app.readLocale(r)
+
+		app.background(func() {
+			err := app.mailer.Send(adminUser.Email, locale, "password_reset", emailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"admin_user": fmt.Sprint(adminUser.ID)})
+			}
+		})
+	}
+
+	message := "an email will be sent to you containing password reset instructions"
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/jesusangelm/api_galeria/internal/data"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+func (app *application) listItemTranslations(w http.ResponseWriter, r *http.Request) {
+	app.listTranslations(w, r, app.models.ItemTranslations)
+}
+
+func (app *application) setItemTranslation(w http.ResponseWriter, r *http.Request) {
+	app.setTranslation(w, r, app.models.ItemTranslations)
+}
+
+func (app *application) deleteItemTranslation(w http.ResponseWriter, r *http.Request) {
+	app.deleteTranslation(w, r, app.models.ItemTranslations)
+}
+
+func (app *application) listCategoryTranslations(w http.ResponseWriter, r *http.Request) {
+	app.listTranslations(w, r, app.models.CategoryTranslations)
+}
+
+func (app *application) setCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	app.setTranslation(w, r, app.models.CategoryTranslations)
+}
+
+func (app *application) deleteCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	app.deleteTranslation(w, r, app.models.CategoryTranslations)
+}
+
+// Shared handlers for the translations of items and categories,
+// the record ID is read from the :id URL parameter
+
+func (app *application) listTranslations(w http.ResponseWriter, r *http.Request, model data.TranslationModel) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	translations, err := model.GetAll(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translations": translations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setTranslation(w http.ResponseWriter, r *http.Request, model data.TranslationModel) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &data.Translation{
+		Locale:      httprouter.ParamsFromContext(r.Context()).ByName("locale"),
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	v := validator.New()
+
+	if data.ValidateTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = model.Upsert(id, translation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTranslation(w http.ResponseWriter, r *http.Request, model data.TranslationModel) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	locale := httprouter.ParamsFromContext(r.Context()).ByName("locale")
+
+	err = model.Delete(id, locale)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,7 +2,9 @@ package data
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -21,8 +23,16 @@ type AdminUser struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
-	Version   int       `json:"-"`
+	// set when the admin user is deactivated, nil while active
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	Version       int        `json:"-"`
+}
+
+// Return true when the admin user was deactivated
+func (a *AdminUser) Deactivated() bool {
+	return a.DeactivatedAt != nil
 }
 
 type password struct {
@@ -37,8 +47,8 @@ type AdminUserModel struct {
 // DB related Utilities
 func (m *AdminUserModel) Insert(adminUser *AdminUser) error {
 	query := `
-    INSERT INTO admin_users (first_name, last_name, email, password_hash, activated)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO admin_users (first_name, last_name, email, password_hash, activated, role)
+    VALUES ($1, $2, $3, $4, $5, $6)
     RETURNING id, created_at, version
   `
 	args := []any{
@@ -47,6 +57,7 @@ func (m *AdminUserModel) Insert(adminUser *AdminUser) error {
 		adminUser.Email,
 		adminUser.Password.hash,
 		adminUser.Activated,
+		adminUser.Role,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -71,7 +82,7 @@ func (m *AdminUserModel) Insert(adminUser *AdminUser) error {
 
 func (m *AdminUserModel) GetByEmail(email string) (*AdminUser, error) {
 	query := `
-    SELECT id, first_name, last_name, email, password_hash, activated, created_at, version
+    SELECT id, first_name, last_name, email, password_hash, activated, role, created_at, deactivated_at, version
     FROM admin_users
     WHERE email = $1
   `
@@ -87,7 +98,9 @@ func (m *AdminUserModel) GetByEmail(email string) (*AdminUser, error) {
 		&adminUser.Email,
 		&adminUser.Password.hash,
 		&adminUser.Activated,
+		&adminUser.Role,
 		&adminUser.CreatedAt,
+		&adminUser.DeactivatedAt,
 		&adminUser.Version,
 	)
 	if err != nil {
@@ -104,7 +117,7 @@ func (m *AdminUserModel) GetByEmail(email string) (*AdminUser, error) {
 
 func (m *AdminUserModel) GetById(id int64) (*AdminUser, error) {
 	query := `
-    SELECT id, first_name, last_name, email, password_hash, activated, created_at, version
+    SELECT id, first_name, last_name, email, password_hash, activated, role, created_at, deactivated_at, version
     FROM admin_users
     WHERE id = $1
   `
@@ -120,7 +133,9 @@ func (m *AdminUserModel) GetById(id int64) (*AdminUser, error) {
 		&adminUser.Email,
 		&adminUser.Password.hash,
 		&adminUser.Activated,
+		&adminUser.Role,
 		&adminUser.CreatedAt,
+		&adminUser.DeactivatedAt,
 		&adminUser.Version,
 	)
 	if err != nil {
@@ -135,6 +150,217 @@ func (m *AdminUserModel) GetById(id int64) (*AdminUser, error) {
 	return &adminUser, nil
 }
 
+// Return the admin user of the token with the given scope, when the token
+// has not expired
+func (m *AdminUserModel) GetForToken(tokenScope, tokenPlaintext string) (*AdminUser, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+    SELECT admin_users.id, admin_users.first_name, admin_users.last_name, admin_users.email,
+      admin_users.password_hash, admin_users.activated, admin_users.role, admin_users.created_at,
+      admin_users.deactivated_at, admin_users.version
+    FROM admin_users
+    INNER JOIN tokens ON tokens.admin_user_id = admin_users.id
+    WHERE tokens.hash = $1 AND tokens.scope = $2 AND tokens.expiry > $3
+  `
+	args := []any{tokenHash[:], tokenScope, time.Now()}
+
+	var adminUser AdminUser
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, args...).Scan(
+		&adminUser.ID,
+		&adminUser.FirstName,
+		&adminUser.LastName,
+		&adminUser.Email,
+		&adminUser.Password.hash,
+		&adminUser.Activated,
+		&adminUser.Role,
+		&adminUser.CreatedAt,
+		&adminUser.DeactivatedAt,
+		&adminUser.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &adminUser, nil
+}
+
+// Update the admin user, deactivated_at is kept when already set so the
+// date of the deactivation does not change
+func (m *AdminUserModel) Update(adminUser *AdminUser) error {
+	query := `
+    UPDATE admin_users
+    SET first_name = $1, last_name = $2, email = $3, password_hash = $4, activated = $5,
+      deactivated_at = CASE WHEN $6 THEN COALESCE(deactivated_at, NOW()) END,
+      role = $7, version = version + 1
+    WHERE id = $8 AND version = $9
+    RETURNING deactivated_at, version
+  `
+	args := []any{
+		adminUser.FirstName,
+		adminUser.LastName,
+		adminUser.Email,
+		adminUser.Password.hash,
+		adminUser.Activated,
+		adminUser.Deactivated(),
+		adminUser.Role,
+		adminUser.ID,
+		adminUser.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&adminUser.DeactivatedAt, &adminUser.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `ERROR: duplicate key value violates unique constraint "admin_users_email_key" (SQLSTATE 23505)`:
+			return ErrDuplicateEmail
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *AdminUserModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    DELETE FROM admin_users
+    WHERE id = $1
+  `
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Return a slice of admin users, search matches their names and email
+func (m *AdminUserModel) List(search string, filters Filters) ([]*AdminUser, Metadata, error) {
+	sortColumn := "admin_users." + filters.sortColumn()
+
+	keyset, keysetArgs, err := filters.keysetCondition(sortColumn, "admin_users.id", 4)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+    SELECT
+      %s, id, first_name, last_name, email, password_hash, activated, role, created_at,
+      deactivated_at, version
+    FROM admin_users
+    WHERE (
+      first_name || ' ' || last_name ILIKE '%%' || $1 || '%%'
+      OR email ILIKE '%%' || $1 || '%%'
+      OR $1 = ''
+    )
+    %s
+    ORDER BY %s %s, admin_users.id ASC
+    LIMIT $2
+    OFFSET $3
+  `, filters.totalRecordsExpr(), keyset, sortColumn, filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// one extra record is fetched to know if there is a next page
+	args := []any{search, filters.limit() + 1, filters.offset()}
+	args = append(args, keysetArgs...)
+
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	adminUsers := []*AdminUser{}
+
+	for rows.Next() {
+		var adminUser AdminUser
+
+		err := rows.Scan(
+			&totalRecords,
+			&adminUser.ID,
+			&adminUser.FirstName,
+			&adminUser.LastName,
+			&adminUser.Email,
+			&adminUser.Password.hash,
+			&adminUser.Activated,
+			&adminUser.Role,
+			&adminUser.CreatedAt,
+			&adminUser.DeactivatedAt,
+			&adminUser.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		adminUsers = append(adminUsers, &adminUser)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	nextCursor := ""
+	if len(adminUsers) > filters.limit() {
+		adminUsers = adminUsers[:filters.limit()]
+		last := adminUsers[len(adminUsers)-1]
+
+		nextCursor, err = filters.encodeCursor(last.sortValue(filters.sortColumn()), last.ID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	var metadata Metadata
+	if filters.Cursor != "" {
+		metadata = calculateCursorMetadata(filters.PageSize, nextCursor)
+	} else {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		metadata.NextCursor = nextCursor
+	}
+
+	return adminUsers, metadata, nil
+}
+
+// Return the value of the given sort column, used to build the cursor
+func (a *AdminUser) sortValue(column string) any {
+	switch column {
+	case "email":
+		return a.Email
+	case "created_at":
+		return a.CreatedAt
+	default:
+		return a.ID
+	}
+}
+
 // Password hashing
 func (p *password) Set(plaintextPassword string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
@@ -187,6 +413,8 @@ func ValidateUser(v *validator.Validator, adminUser *AdminUser) {
 		ValidatePasswordPlaintext(v, *adminUser.Password.plaintext)
 	}
 
+	v.Check(validator.PermittedValue(adminUser.Role, Roles...), "role", "must be owner, editor or viewer")
+
 	if adminUser.Password.hash == nil {
 		panic("missing password hash for admin user")
 	}
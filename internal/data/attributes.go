@@ -0,0 +1,71 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Permitted types for the custom attributes of the items
+var AttributeTypes = []string{"string", "number", "boolean"}
+
+// Definition of a custom attribute, the attribute schema of a category
+// is a list of them and the items of the category are validated against it
+type AttributeDefinition struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+func ValidateAttributeSchema(v *validator.Validator, schema []AttributeDefinition) {
+	names := make([]string, 0, len(schema))
+
+	for i, definition := range schema {
+		key := fmt.Sprintf("attribute_schema[%d]", i)
+
+		v.Check(definition.Name != "", key+".name", "must be provided")
+		v.Check(len(definition.Name) <= 50, key+".name", "must not be more than 50 bytes long")
+		v.Check(validator.PermittedValue(definition.Type, AttributeTypes...), key+".type", "invalid type value")
+		v.Check(len(definition.AllowedValues) == 0 || definition.Type == "string", key+".allowed_values", "only permitted for string attributes")
+
+		names = append(names, definition.Name)
+	}
+
+	v.Check(validator.Unique(names), "attribute_schema", "must not contain duplicate names")
+}
+
+// Validate the custom attributes of an item against the attribute schema of its category
+func ValidateItemAttributes(v *validator.Validator, attributes map[string]any, schema []AttributeDefinition) {
+	defined := make(map[string]bool, len(schema))
+
+	for _, definition := range schema {
+		defined[definition.Name] = true
+		key := "attributes." + definition.Name
+
+		value, exists := attributes[definition.Name]
+		if !exists {
+			v.Check(!definition.Required, key, "must be provided")
+			continue
+		}
+
+		switch definition.Type {
+		case "string":
+			s, ok := value.(string)
+			v.Check(ok, key, "must be a string")
+			if ok && len(definition.AllowedValues) > 0 {
+				v.Check(validator.PermittedValue(s, definition.AllowedValues...), key, "invalid value")
+			}
+		case "number":
+			_, ok := value.(float64)
+			v.Check(ok, key, "must be a number")
+		case "boolean":
+			_, ok := value.(bool)
+			v.Check(ok, key, "must be a boolean")
+		}
+	}
+
+	for name := range attributes {
+		v.Check(defined[name], "attributes."+name, "is not defined for the category")
+	}
+}
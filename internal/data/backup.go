@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDatabaseNotEmpty = errors.New("database not empty")
+
+// Tables saved in a backup, in an order that satisfies the foreign keys.
+// The import jobs and the tokens are not saved, they are only useful for a
+// short time.
+var BackupTables = []string{
+	"admin_users",
+	"categories",
+	"items",
+	"item_attachments",
+	"category_attachments",
+	"item_variants",
+	"item_translations",
+	"category_translations",
+	"collections",
+	"collection_items",
+	"featured_items",
+	"item_revisions",
+}
+
+// Tables of BackupTables without an id column, they have no sequence to restore
+var backupTablesWithoutID = map[string]bool{
+	"item_translations":     true,
+	"category_translations": true,
+	"collection_items":      true,
+	"featured_items":        true,
+}
+
+// Dumps the tables as JSON, independent of the SQL dialect and the column
+// order, and restores them in an empty database
+type BackupModel struct {
+	DB *pgxpool.Pool
+}
+
+// The pool, or the transaction of a snapshot
+type backupQuerier interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}
+
+// Begin a read only transaction in which all the tables are read from the
+// same snapshot, so the rows of a backup satisfy the foreign keys
+func (m *BackupModel) BeginSnapshot(ctx context.Context) (pgx.Tx, error) {
+	return m.DB.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+}
+
+// Call fn with every row of the table as a JSON object
+func (m *BackupModel) DumpTable(ctx context.Context, db backupQuerier, table string, fn func(row []byte) error) error {
+	query := fmt.Sprintf(`
+		SELECT row_to_json(%[1]s)
+		FROM %[1]s
+	`, pgx.Identifier{table}.Sanitize())
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		err := rows.Scan(&row)
+		if err != nil {
+			return err
+		}
+
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Return the keys of every file in S3 referenced by the database
+func (m *BackupModel) StorageKeys(ctx context.Context, db backupQuerier) ([]string, error) {
+	query := `
+		SELECT key FROM item_attachments
+		UNION
+		SELECT key FROM category_attachments
+		ORDER BY key
+	`
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Return ErrDatabaseNotEmpty when any of the tables of BackupTables has rows
+func (m *BackupModel) CheckEmpty(ctx context.Context) error {
+	return checkEmpty(ctx, m.DB)
+}
+
+func checkEmpty(ctx context.Context, db interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}) error {
+	for _, table := range BackupTables {
+		var exists bool
+
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", pgx.Identifier{table}.Sanitize())
+
+		err := db.QueryRow(ctx, query).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrDatabaseNotEmpty
+		}
+	}
+
+	return nil
+}
+
+// Insert the rows of every table of BackupTables in a single transaction.
+// read is called once per table, in order, and calls insert for every row.
+// ErrDatabaseNotEmpty is returned when any of the tables has rows.
+func (m *BackupModel) Restore(ctx context.Context, read func(table string, insert func(row []byte) error) error) error {
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	err = checkEmpty(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// the cover of a category references an item attachment, restored
+	// after the category, so the covers are set at the end
+	covers := make(map[int64]int64)
+
+	for _, table := range BackupTables {
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s
+			SELECT * FROM jsonb_populate_record(NULL::%[1]s, $1::jsonb)
+		`, pgx.Identifier{table}.Sanitize())
+
+		if table == "categories" {
+			query = `
+				INSERT INTO categories
+				SELECT * FROM jsonb_populate_record(NULL::categories, $1::jsonb || '{"cover_item_attachment_id": null}')
+			`
+		}
+
+		err = read(table, func(row []byte) error {
+			if table == "categories" {
+				var category struct {
+					ID    int64  `json:"id"`
+					Cover *int64 `json:"cover_item_attachment_id"`
+				}
+
+				err := json.Unmarshal(row, &category)
+				if err != nil {
+					return err
+				}
+
+				if category.Cover != nil {
+					covers[category.ID] = *category.Cover
+				}
+			}
+
+			_, err := tx.Exec(ctx, query, string(row))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("restoring %s: %w", table, err)
+		}
+	}
+
+	for categoryID, attachmentID := range covers {
+		_, err = tx.Exec(ctx, "UPDATE categories SET cover_item_attachment_id = $2 WHERE id = $1", categoryID, attachmentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the sequences continue after the restored IDs
+	for _, table := range BackupTables {
+		if backupTablesWithoutID[table] {
+			continue
+		}
+
+		query := fmt.Sprintf(`
+			SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL)
+			FROM %[1]s
+		`, table)
+
+		_, err = tx.Exec(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/jesusangelm/api_galeria/internal/validator"
 )
 
-var ErrDuplicateName = errors.New("duplicate name")
+var (
+	ErrDuplicateName    = errors.New("duplicate name")
+	ErrInvalidItemOrder = errors.New("invalid item order")
+)
 
 // struct to represent the Category model
 type Category struct {
@@ -24,6 +27,19 @@ type Category struct {
 	Version     int32     `json:"version"`
 	Items       []*Item   `json:"items,omitempty"`
 	ItemsCount  int64     `json:"items_count"`
+	CoverURL    string    `json:"cover_url,omitempty"` // uploaded cover or the image of one of its items
+	// custom attributes allowed on the items of the category
+	AttributeSchema []AttributeDefinition `json:"attribute_schema"`
+	// admin users who created and last updated the category, nil when unknown
+	CreatedBy *int64 `json:"created_by,omitempty"`
+	UpdatedBy *int64 `json:"updated_by,omitempty"`
+}
+
+// An item of the manual order of a category, a zero Version skips the
+// optimistic locking check of the item
+type ItemOrder struct {
+	ID      int64 `json:"id"`
+	Version int32 `json:"version"`
 }
 
 type CategoryModel struct {
@@ -31,14 +47,19 @@ type CategoryModel struct {
 	S3Manager filestorage.S3
 }
 
-// Insert in DB a new Category based on the category given
-func (m *CategoryModel) Insert(category *Category) error {
+// Insert in DB a new Category based on the category given, created by the
+// given admin user (0 when unknown)
+func (m *CategoryModel) Insert(category *Category, adminUserID int64) error {
 	query := `
-		INSERT INTO categories (name, description)
-		VALUES ($1, $2)
-		RETURNING id, created_at, version
+		INSERT INTO categories (name, description, attribute_schema, created_by, updated_by)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($4, 0))
+		RETURNING id, created_at, version, created_by, updated_by
 	`
-	args := []interface{}{category.Name, category.Description}
+	if category.AttributeSchema == nil {
+		category.AttributeSchema = []AttributeDefinition{}
+	}
+
+	args := []interface{}{category.Name, category.Description, category.AttributeSchema, adminUserID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -49,11 +70,13 @@ func (m *CategoryModel) Insert(category *Category) error {
 		&category.ID,
 		&category.CreatedAt,
 		&category.Version,
+		&category.CreatedBy,
+		&category.UpdatedBy,
 	)
 
 	if err != nil {
 		switch {
-		case err.Error() == `ERROR: duplicate key value violates unique constraint "categories_name_key" (SQLSTATE 23505)`:
+		case err.Error() == `ERROR: duplicate key value violates unique constraint "categories_name_idx" (SQLSTATE 23505)`:
 			return ErrDuplicateName
 		default:
 			return err
@@ -63,8 +86,14 @@ func (m *CategoryModel) Insert(category *Category) error {
 	return nil
 }
 
-// Return a single category based on the ID given
+// Return a single category based on the ID given, with the content in the default locale
 func (m *CategoryModel) Get(id int64) (*Category, error) {
+	return m.GetLocalized(id, DefaultLocale)
+}
+
+// Return a single category based on the ID given, with the names and descriptions
+// in the given locale, falling back to the default locale when there is no translation
+func (m *CategoryModel) GetLocalized(id int64, locale string) (*Category, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
@@ -74,22 +103,39 @@ func (m *CategoryModel) Get(id int64) (*Category, error) {
 
 	query := `
 		SELECT
-			categories.id, categories.name, categories.description,
-			categories.created_at, categories.version, COUNT(items.id) AS items_count
+			categories.id, COALESCE(category_translations.name, categories.name) AS name,
+			COALESCE(category_translations.description, categories.description) AS description,
+			categories.created_at, categories.version, categories.attribute_schema,
+			categories.created_by, categories.updated_by, COUNT(items.id) AS items_count,
+			COALESCE(category_attachments.key, cover_item_attachments.key, '') AS cover_key
 		FROM categories
-		LEFT JOIN items ON categories.id = items.category_id
-		WHERE categories.id = $1
-		GROUP BY categories.id
+		LEFT JOIN items ON categories.id = items.category_id AND items.deleted_at IS NULL
+		LEFT JOIN category_attachments ON category_attachments.category_id = categories.id
+		LEFT JOIN (
+			item_attachments AS cover_item_attachments
+			INNER JOIN items AS cover_items
+				ON cover_items.id = cover_item_attachments.item_id AND cover_items.deleted_at IS NULL
+		) ON cover_item_attachments.id = categories.cover_item_attachment_id
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $2
+		WHERE categories.id = $1 AND categories.deleted_at IS NULL
+		GROUP BY categories.id, category_translations.name, category_translations.description,
+			category_attachments.key, cover_item_attachments.key
 	`
 	var category Category
+	var coverKey string
 
-	err := m.DB.QueryRow(ctx, query, id).Scan(
+	err := m.DB.QueryRow(ctx, query, id, locale).Scan(
 		&category.ID,
 		&category.Name,
 		&category.Description,
 		&category.CreatedAt,
 		&category.Version,
+		&category.AttributeSchema,
+		&category.CreatedBy,
+		&category.UpdatedBy,
 		&category.ItemsCount,
+		&coverKey,
 	)
 	if err != nil {
 		switch {
@@ -99,17 +145,22 @@ func (m *CategoryModel) Get(id int64) (*Category, error) {
 			return nil, err
 		}
 	}
+	category.CoverURL = m.S3Manager.GetFileUrl(coverKey)
 
 	// query to get the items in a given category
 	query = `
-		SELECT items.id, items.name, items.description, items.created_at,
-				items.version, COALESCE(item_attachments.filename, '') as filename
+		SELECT items.id, COALESCE(item_translations.name, items.name) AS name,
+				COALESCE(item_translations.description, items.description) AS description,
+				items.created_at, items.version, items.price, items.currency, items.availability,
+				items.stock, items.position, COALESCE(item_attachments.filename, '') as filename
 		FROM items
 		LEFT JOIN item_attachments ON items.id = item_attachments.item_id
-		WHERE items.category_id = $1
-		ORDER BY items.created_at DESC
+		LEFT JOIN item_translations
+			ON item_translations.item_id = items.id AND item_translations.locale = $2
+		WHERE items.category_id = $1 AND items.deleted_at IS NULL
+		ORDER BY items.position ASC, items.created_at DESC
 	`
-	rows, err := m.DB.Query(ctx, query, id)
+	rows, err := m.DB.Query(ctx, query, id, locale)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +175,11 @@ func (m *CategoryModel) Get(id int64) (*Category, error) {
 			&item.Description,
 			&item.CreatedAt,
 			&item.Version,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.Stock,
+			&item.Position,
 			&item.ItemAttachment.Filename,
 		)
 		if err != nil {
@@ -140,25 +196,32 @@ func (m *CategoryModel) Get(id int64) (*Category, error) {
 	return &category, nil
 }
 
-func (m *CategoryModel) Update(category *Category) error {
+// Update the category, last updated by the given admin user (0 when unknown)
+func (m *CategoryModel) Update(category *Category, adminUserID int64) error {
 	query := `
 		UPDATE categories
-		SET name = $1, description = $2, version = version + 1
-		WHERE id = $3 AND version = $4
-		RETURNING version
+		SET name = $1, description = $2, attribute_schema = $3, updated_by = NULLIF($6, 0),
+			version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+		RETURNING version, updated_by
 	`
+	if category.AttributeSchema == nil {
+		category.AttributeSchema = []AttributeDefinition{}
+	}
 
 	args := []any{
 		category.Name,
 		category.Description,
+		category.AttributeSchema,
 		category.ID,
 		category.Version,
+		adminUserID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRow(ctx, query, args...).Scan(&category.Version)
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&category.Version, &category.UpdatedBy)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -171,53 +234,311 @@ func (m *CategoryModel) Update(category *Category) error {
 	return nil
 }
 
+// Set the manual order of the items of the category, items must contain
+// all the items of the category in the desired order. The category version
+// is checked and incremented, so concurrent reorders result in ErrEditConflict,
+// as does an item whose version does not match.
+func (m *CategoryModel) ReorderItems(category *Category, items []ItemOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE categories
+		SET version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING version
+	`
+
+	err = tx.QueryRow(ctx, query, category.ID, category.Version).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	ids := make([]int64, len(items))
+	versions := make([]int32, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		versions[i] = item.Version
+	}
+
+	// the positions follow the order of the given items, starting at 1
+	query = `
+		UPDATE items
+		SET position = ordered.position
+		FROM unnest($2::bigint[], $3::integer[]) WITH ORDINALITY AS ordered(id, version, position)
+		WHERE items.id = ordered.id AND items.category_id = $1 AND items.deleted_at IS NULL
+		AND (ordered.version = 0 OR items.version = ordered.version)
+	`
+
+	result, err := tx.Exec(ctx, query, category.ID, ids, versions)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() != int64(len(items)) {
+		// SQL query to know if an item was skipped because of its version
+		query = `
+			SELECT EXISTS (
+				SELECT 1
+				FROM items
+				INNER JOIN unnest($2::bigint[], $3::integer[]) AS given(id, version) ON given.id = items.id
+				WHERE items.category_id = $1 AND items.deleted_at IS NULL
+				AND given.version <> 0 AND items.version <> given.version
+			)
+		`
+
+		var conflict bool
+
+		err = tx.QueryRow(ctx, query, category.ID, ids, versions).Scan(&conflict)
+		if err != nil {
+			return err
+		}
+
+		if conflict {
+			return ErrEditConflict
+		}
+	}
+
+	var itemsCount int64
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM items WHERE category_id = $1 AND deleted_at IS NULL", category.ID).Scan(&itemsCount)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() != int64(len(items)) || itemsCount != int64(len(items)) {
+		return ErrInvalidItemOrder
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Move the category to the trash, its items are trashed along with it
+// using the same timestamp, so they can be restored together.
 func (m *CategoryModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
 	query := `
-		DELETE FROM categories
-		WHERE id = $1
+		UPDATE categories
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at
 	`
 
+	var deletedAt time.Time
+
+	err = tx.QueryRow(ctx, query, id).Scan(&deletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `
+		UPDATE items
+		SET deleted_at = $2
+		WHERE category_id = $1 AND deleted_at IS NULL
+	`
+
+	_, err = tx.Exec(ctx, query, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Restore the category from the trash, along with the items that were
+// trashed when the category was deleted. Return ErrDuplicateName when a
+// live category has taken its name in the meantime.
+func (m *CategoryModel) Restore(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.Exec(ctx, query, id)
+	tx, err := m.DB.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
 
-	rowsAffected := result.RowsAffected()
+	query := `
+		UPDATE categories
+		SET deleted_at = NULL
+		FROM (SELECT id, deleted_at FROM categories WHERE id = $1 FOR UPDATE) AS trashed
+		WHERE categories.id = trashed.id AND categories.deleted_at IS NOT NULL
+		RETURNING trashed.deleted_at
+	`
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	var deletedAt time.Time
+
+	err = tx.QueryRow(ctx, query, id).Scan(&deletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrRecordNotFound
+		case err.Error() == `ERROR: duplicate key value violates unique constraint "categories_name_idx" (SQLSTATE 23505)`:
+			return ErrDuplicateName
+		default:
+			return err
+		}
 	}
 
-	return nil
+	query = `
+		UPDATE items
+		SET deleted_at = NULL
+		WHERE category_id = $1 AND deleted_at = $2
+	`
+
+	_, err = tx.Exec(ctx, query, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Return the category with the given name, without its items
+func (m *CategoryModel) GetByName(name string) (*Category, error) {
+	query := `
+		SELECT id, name, description, created_at, version, attribute_schema
+		FROM categories
+		WHERE name = $1 AND deleted_at IS NULL
+	`
+
+	var category Category
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, name).Scan(
+		&category.ID,
+		&category.Name,
+		&category.Description,
+		&category.CreatedAt,
+		&category.Version,
+		&category.AttributeSchema,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+// Return the attribute schema of the category with the given ID
+func (m *CategoryModel) GetAttributeSchema(id int64) ([]AttributeDefinition, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT attribute_schema
+		FROM categories
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	var schema []AttributeDefinition
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, id).Scan(&schema)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return schema, nil
 }
 
 // Return a slice of categories.
-func (m *CategoryModel) List(name string, filters Filters) ([]*Category, Metadata, error) {
+// The names and descriptions are in the given locale, falling back to the default locale.
+func (m *CategoryModel) List(name string, locale string, filters Filters) ([]*Category, Metadata, error) {
+	sortColumn := "categories." + filters.sortColumn()
+	if filters.sortColumn() == "name" {
+		// sorted by the returned name, the one saved in the cursor
+		sortColumn = "COALESCE(category_translations.name, categories.name)"
+	}
+
+	keyset, keysetArgs, err := filters.keysetCondition(sortColumn, "categories.id", 5)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	query := fmt.Sprintf(`
 		SELECT
-			count(*) OVER(), categories.id, categories.name, categories.description,
-			categories.created_at, categories.version, COUNT(items.id) AS items_count
+			%s, categories.id, COALESCE(category_translations.name, categories.name) AS name,
+			COALESCE(category_translations.description, categories.description) AS description,
+			categories.created_at, categories.version, categories.attribute_schema,
+			categories.created_by, categories.updated_by, COUNT(items.id) AS items_count,
+			COALESCE(category_attachments.key, cover_item_attachments.key, '') AS cover_key
 		FROM categories
-		LEFT JOIN items ON categories.id = items.category_id
-		WHERE (to_tsvector('simple', categories.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		GROUP BY categories.id
-		ORDER BY %s %s, id ASC
+		LEFT JOIN items ON categories.id = items.category_id AND items.deleted_at IS NULL
+		LEFT JOIN category_attachments ON category_attachments.category_id = categories.id
+		LEFT JOIN (
+			item_attachments AS cover_item_attachments
+			INNER JOIN items AS cover_items
+				ON cover_items.id = cover_item_attachments.item_id AND cover_items.deleted_at IS NULL
+		) ON cover_item_attachments.id = categories.cover_item_attachment_id
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $4
+		WHERE categories.deleted_at IS NULL
+		AND (to_tsvector('simple', categories.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		%s
+		GROUP BY categories.id, category_translations.name, category_translations.description,
+			category_attachments.key, cover_item_attachments.key
+		ORDER BY %s %s, categories.id ASC
 		LIMIT $2
 		OFFSET $3
-	`, filters.sortColumn(), filters.sortDirection())
+	`, filters.totalRecordsExpr(), keyset, sortColumn, filters.sortDirection())
 
 	// 3 seconds timeout for quering the DB
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{name, filters.limit(), filters.offset()}
+	// one extra record is fetched to know if there is a next page
+	args := []any{name, filters.limit() + 1, filters.offset(), locale}
+	args = append(args, keysetArgs...)
 
 	rows, err := m.DB.Query(ctx, query, args...)
 	if err != nil {
@@ -230,6 +551,8 @@ func (m *CategoryModel) List(name string, filters Filters) ([]*Category, Metadat
 
 	for rows.Next() {
 		var category Category
+		var coverKey string
+
 		err := rows.Scan(
 			&totalRecords,
 			&category.ID,
@@ -237,11 +560,16 @@ func (m *CategoryModel) List(name string, filters Filters) ([]*Category, Metadat
 			&category.Description,
 			&category.CreatedAt,
 			&category.Version,
+			&category.AttributeSchema,
+			&category.CreatedBy,
+			&category.UpdatedBy,
 			&category.ItemsCount,
+			&coverKey,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		category.CoverURL = m.S3Manager.GetFileUrl(coverKey)
 
 		categories = append(categories, &category)
 	}
@@ -249,15 +577,46 @@ func (m *CategoryModel) List(name string, filters Filters) ([]*Category, Metadat
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	nextCursor := ""
+	if len(categories) > filters.limit() {
+		categories = categories[:filters.limit()]
+		last := categories[len(categories)-1]
+
+		nextCursor, err = filters.encodeCursor(last.sortValue(filters.sortColumn()), last.ID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	var metadata Metadata
+	if filters.Cursor != "" {
+		metadata = calculateCursorMetadata(filters.PageSize, nextCursor)
+	} else {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		metadata.NextCursor = nextCursor
+	}
 
 	return categories, metadata, nil
 }
 
+// Return the value of the given sort column, used to build the cursor
+func (c *Category) sortValue(column string) any {
+	switch column {
+	case "name":
+		return c.Name
+	case "created_at":
+		return c.CreatedAt
+	default:
+		return c.ID
+	}
+}
+
 func ValidateCategory(v *validator.Validator, category *Category) {
 	v.Check(category.Name != "", "name", "must be provided")
 	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
 
 	v.Check(category.Description != "", "description", "must be provided")
 	v.Check(len(category.Description) <= 500, "description", "must not be more than 500 bytes long")
+
+	ValidateAttributeSchema(v, category.AttributeSchema)
 }
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+)
+
+// struct to represent the uploaded cover image of a Category
+type CategoryAttachment struct {
+	ID          int64     `json:"id,omitempty"`
+	Key         string    `json:"key,omitempty"`
+	Filename    string    `json:"filename,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	ByteSize    int64     `json:"byte_size,omitempty"`
+	CreatedAt   time.Time `json:"-"`
+	CategoryID  int64     `json:"category_id,omitempty"`
+}
+
+type CategoryAttachmentModel struct {
+	DB        *pgxpool.Pool
+	S3Manager filestorage.S3
+}
+
+// Set the uploaded file as the cover of the category, replacing the
+// previous cover. The file of the previous uploaded cover is deleted from S3.
+func (m *CategoryAttachmentModel) Insert(attachment *CategoryAttachment) error {
+	query := `
+		INSERT INTO category_attachments (key, filename, content_type, byte_size, category_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	args := []any{
+		attachment.Key,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.ByteSize,
+		attachment.CategoryID,
+	}
+
+	return m.replaceCover(attachment.CategoryID, func(ctx context.Context, tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+	})
+}
+
+// Set the image of one of the items of the category as its cover, replacing the
+// previous cover. ErrInvalidAttachment is returned when the attachment is not
+// of an item of the category.
+func (m *CategoryAttachmentModel) SetFromItemAttachment(categoryID, itemAttachmentID int64) error {
+	query := `
+		UPDATE categories
+		SET cover_item_attachment_id = $2
+		WHERE id = $1 AND deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM item_attachments
+			INNER JOIN items ON items.id = item_attachments.item_id
+			WHERE item_attachments.id = $2 AND items.category_id = $1 AND items.deleted_at IS NULL
+		)
+	`
+
+	return m.replaceCover(categoryID, func(ctx context.Context, tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, categoryID, itemAttachmentID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrInvalidAttachment
+		}
+
+		return nil
+	})
+}
+
+// Remove the cover of the category
+func (m *CategoryAttachmentModel) Delete(categoryID int64) error {
+	return m.replaceCover(categoryID, func(ctx context.Context, tx pgx.Tx) error {
+		return nil
+	})
+}
+
+// Remove the current cover of the category, uploaded or picked from its
+// items, and run setCover in the same transaction. The file of the removed
+// uploaded cover is deleted from S3 after the transaction is committed.
+func (m *CategoryAttachmentModel) replaceCover(categoryID int64, setCover func(context.Context, pgx.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, "UPDATE categories SET cover_item_attachment_id = NULL WHERE id = $1 AND deleted_at IS NULL", categoryID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	var oldKey string
+	err = tx.QueryRow(ctx, "DELETE FROM category_attachments WHERE category_id = $1 RETURNING key", categoryID).Scan(&oldKey)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	err = setCover(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	if oldKey != "" {
+		return m.S3Manager.DeleteFile(oldKey)
+	}
+
+	return nil
+}
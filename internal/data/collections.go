@@ -0,0 +1,419 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+var ErrInvalidCollectionItems = errors.New("invalid collection items")
+
+// struct to represent a curated collection of items from any category
+type Collection struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	CoverAttachmentID *int64    `json:"cover_attachment_id,omitempty"`
+	CoverURL          string    `json:"cover_url,omitempty"` // extracted from join with item_attachments table
+	Published         bool      `json:"published"`
+	CreatedAt         time.Time `json:"created_at"`
+	Version           int32     `json:"version"`
+	ItemIDs           []int64   `json:"item_ids,omitempty"` // ordered IDs of the items in the collection
+	Items             []*Item   `json:"items,omitempty"`
+	ItemsCount        int64     `json:"items_count"`
+}
+
+type CollectionModel struct {
+	DB        *pgxpool.Pool
+	S3Manager filestorage.S3
+}
+
+// Insert in DB a new Collection, with its items, based on the collection given
+func (m *CollectionModel) Insert(collection *Collection) error {
+	query := `
+		INSERT INTO collections (name, description, cover_attachment_id, published)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version
+	`
+	args := []any{
+		collection.Name,
+		collection.Description,
+		collection.CoverAttachmentID,
+		collection.Published,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+		&collection.Version,
+	)
+	if err != nil {
+		return collectionError(err)
+	}
+
+	err = m.setItems(ctx, tx, collection)
+	if err != nil {
+		return err
+	}
+
+	collection.ItemsCount = int64(len(collection.ItemIDs))
+
+	return tx.Commit(ctx)
+}
+
+// Return a single collection, with its items in order, based on the ID given
+func (m *CollectionModel) Get(id int64) (*Collection, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT
+			collections.id, collections.name, collections.description,
+			collections.cover_attachment_id, COALESCE(item_attachments.key, '') AS cover_key,
+			collections.published, collections.created_at, collections.version
+		FROM collections
+		LEFT JOIN item_attachments ON item_attachments.id = collections.cover_attachment_id
+		WHERE collections.id = $1
+	`
+
+	var collection Collection
+	var coverKey string
+
+	err := m.DB.QueryRow(ctx, query, id).Scan(
+		&collection.ID,
+		&collection.Name,
+		&collection.Description,
+		&collection.CoverAttachmentID,
+		&coverKey,
+		&collection.Published,
+		&collection.CreatedAt,
+		&collection.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	collection.CoverURL = m.S3Manager.GetFileUrl(coverKey)
+
+	// query to get the items in the collection
+	query = `
+		SELECT items.id, items.name, items.description, items.created_at, items.version,
+				items.category_id, items.price, items.currency, items.availability, items.stock,
+				COALESCE(item_attachments.filename, '') AS filename,
+				COALESCE(item_attachments.key, '') AS key
+		FROM collection_items
+		INNER JOIN items ON items.id = collection_items.item_id
+		LEFT JOIN item_attachments ON items.id = item_attachments.item_id
+		WHERE collection_items.collection_id = $1 AND items.deleted_at IS NULL
+		ORDER BY collection_items.position ASC
+	`
+	rows, err := m.DB.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collection.ItemIDs = []int64{}
+
+	for rows.Next() {
+		var item Item
+		err := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&item.Description,
+			&item.CreatedAt,
+			&item.Version,
+			&item.CategoryID,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.Stock,
+			&item.ItemAttachment.Filename,
+			&item.ItemAttachment.Key,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		item.ImageURL = m.S3Manager.GetFileUrl(item.ItemAttachment.Key)
+
+		collection.Items = append(collection.Items, &item)
+		collection.ItemIDs = append(collection.ItemIDs, item.ID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	collection.ItemsCount = int64(len(collection.ItemIDs))
+
+	return &collection, nil
+}
+
+// Update the collection, and replace its items with collection.ItemIDs when
+// replaceItems is true
+func (m *CollectionModel) Update(collection *Collection, replaceItems bool) error {
+	query := `
+		UPDATE collections
+		SET name = $1, description = $2, cover_attachment_id = $3, published = $4,
+			version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version
+	`
+
+	args := []any{
+		collection.Name,
+		collection.Description,
+		collection.CoverAttachmentID,
+		collection.Published,
+		collection.ID,
+		collection.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return collectionError(err)
+		}
+	}
+
+	if replaceItems {
+		err = m.setItems(ctx, tx, collection)
+		if err != nil {
+			return err
+		}
+
+		collection.ItemsCount = int64(len(collection.ItemIDs))
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *CollectionModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM collections
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Return a slice of collections, published filters by the published flag when not nil
+func (m *CollectionModel) List(name string, published *bool, filters Filters) ([]*Collection, Metadata, error) {
+	sortColumn := "collections." + filters.sortColumn()
+
+	keyset, keysetArgs, err := filters.keysetCondition(sortColumn, "collections.id", 5)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s, collections.id, collections.name, collections.description,
+			collections.cover_attachment_id, COALESCE(item_attachments.key, '') AS cover_key,
+			collections.published, collections.created_at, collections.version,
+			(
+				SELECT COUNT(*) FROM collection_items
+				INNER JOIN items ON items.id = collection_items.item_id
+				WHERE collection_items.collection_id = collections.id AND items.deleted_at IS NULL
+			) AS items_count
+		FROM collections
+		LEFT JOIN item_attachments ON item_attachments.id = collections.cover_attachment_id
+		WHERE (to_tsvector('simple', collections.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		AND (collections.published = $4 OR $4 IS NULL)
+		%s
+		ORDER BY %s %s, collections.id ASC
+		LIMIT $2
+		OFFSET $3
+	`, filters.totalRecordsExpr(), keyset, sortColumn, filters.sortDirection())
+
+	// 3 seconds timeout for quering the DB
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// one extra record is fetched to know if there is a next page
+	args := []any{name, filters.limit() + 1, filters.offset(), published}
+	args = append(args, keysetArgs...)
+
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var collections []*Collection
+
+	for rows.Next() {
+		var collection Collection
+		var coverKey string
+
+		err := rows.Scan(
+			&totalRecords,
+			&collection.ID,
+			&collection.Name,
+			&collection.Description,
+			&collection.CoverAttachmentID,
+			&coverKey,
+			&collection.Published,
+			&collection.CreatedAt,
+			&collection.Version,
+			&collection.ItemsCount,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		collection.CoverURL = m.S3Manager.GetFileUrl(coverKey)
+
+		collections = append(collections, &collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	nextCursor := ""
+	if len(collections) > filters.limit() {
+		collections = collections[:filters.limit()]
+		last := collections[len(collections)-1]
+
+		nextCursor, err = filters.encodeCursor(last.sortValue(filters.sortColumn()), last.ID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	var metadata Metadata
+	if filters.Cursor != "" {
+		metadata = calculateCursorMetadata(filters.PageSize, nextCursor)
+	} else {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		metadata.NextCursor = nextCursor
+	}
+
+	return collections, metadata, nil
+}
+
+// Replace the items of the collection with collection.ItemIDs, in that order.
+// The trashed items are not listed in collection.ItemIDs, they are kept in
+// the collection so they are back in it when restored.
+func (m *CollectionModel) setItems(ctx context.Context, tx pgx.Tx, collection *Collection) error {
+	query := `
+		DELETE FROM collection_items
+		USING items
+		WHERE collection_items.collection_id = $1
+		AND items.id = collection_items.item_id AND items.deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(ctx, query, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	if collection.ItemIDs == nil {
+		collection.ItemIDs = []int64{}
+	}
+
+	// a kept trashed item may be listed again
+	query = `
+		INSERT INTO collection_items (collection_id, item_id, position)
+		SELECT $1, ordered.id, ordered.position
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS ordered(id, position)
+		ON CONFLICT (collection_id, item_id) DO UPDATE SET position = EXCLUDED.position
+	`
+
+	_, err = tx.Exec(ctx, query, collection.ID, collection.ItemIDs)
+	if err != nil {
+		return collectionError(err)
+	}
+
+	return nil
+}
+
+// Return the value of the given sort column, used to build the cursor
+func (c *Collection) sortValue(column string) any {
+	switch column {
+	case "name":
+		return c.Name
+	case "created_at":
+		return c.CreatedAt
+	default:
+		return c.ID
+	}
+}
+
+// Translate the constraint errors of the collections tables
+func collectionError(err error) error {
+	switch {
+	case err.Error() == `ERROR: duplicate key value violates unique constraint "collections_name_key" (SQLSTATE 23505)`:
+		return ErrDuplicateName
+	case err.Error() == `ERROR: insert or update on table "collections" violates foreign key constraint "collections_cover_attachment_id_fkey" (SQLSTATE 23503)`:
+		return ErrInvalidAttachment
+	case err.Error() == `ERROR: insert or update on table "collection_items" violates foreign key constraint "collection_items_item_id_fkey" (SQLSTATE 23503)`:
+		return ErrInvalidCollectionItems
+	default:
+		return err
+	}
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Name != "", "name", "must be provided")
+	v.Check(len(collection.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(collection.Description != "", "description", "must be provided")
+	v.Check(len(collection.Description) <= 500, "description", "must not be more than 500 bytes long")
+
+	v.Check(len(collection.ItemIDs) <= 500, "item_ids", "must not contain more than 500 items")
+	v.Check(validator.Unique(collection.ItemIDs), "item_ids", "must not contain duplicate values")
+}
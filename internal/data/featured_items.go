@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// struct to represent an item featured on the homepage, between
+// StartsAt and EndsAt when set, the highest priority first
+type FeaturedItem struct {
+	ItemID    int64      `json:"item_id"`
+	Priority  int32      `json:"priority"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type FeaturedItemModel struct {
+	DB *pgxpool.Pool
+}
+
+// Feature the item, or replace the priority and dates if it is already featured.
+// ErrRecordNotFound is returned when the item does not exist.
+func (m *FeaturedItemModel) Upsert(featured *FeaturedItem) error {
+	query := `
+		INSERT INTO featured_items (item_id, priority, starts_at, ends_at)
+		SELECT id, $2, $3, $4 FROM items WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (item_id)
+		DO UPDATE SET priority = EXCLUDED.priority, starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at
+		RETURNING created_at
+	`
+
+	args := []any{featured.ItemID, featured.Priority, featured.StartsAt, featured.EndsAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// no row is returned when the item does not exist
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&featured.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *FeaturedItemModel) Delete(itemID int64) error {
+	if itemID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM featured_items
+		WHERE item_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, itemID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateFeaturedItem(v *validator.Validator, featured *FeaturedItem) {
+	v.Check(featured.Priority >= 0, "priority", "must be zero or greater")
+	v.Check(featured.Priority <= 1000, "priority", "must be a maximum of 1000")
+
+	if featured.StartsAt != nil && featured.EndsAt != nil {
+		v.Check(featured.EndsAt.After(*featured.StartsAt), "ends_at", "must be after starts_at")
+	}
+}
@@ -1,25 +1,42 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"github.com/jesusangelm/api_galeria/internal/validator"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	Cursor       string
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of the opaque cursor used for keyset pagination.
+// It holds the sort value and the id of the last record of the previous page.
+type cursor struct {
+	Sort  string          `json:"sort"`
+	Value json.RawMessage `json:"value"`
+	ID    int64           `json:"id"`
 }
 
 func (f Filters) sortColumn() string {
@@ -33,6 +50,11 @@ func (f Filters) sortColumn() string {
 }
 
 func (f Filters) sortDirection() string {
+	// the most relevant results always come first
+	if f.sortColumn() == "relevance" {
+		return "DESC"
+	}
+
 	if strings.HasPrefix(f.Sort, "-") {
 		return "DESC"
 	}
@@ -45,9 +67,109 @@ func (f Filters) limit() int {
 }
 
 func (f Filters) offset() int {
+	// the cursor already points to the first record of the page
+	if f.Cursor != "" {
+		return 0
+	}
+
 	return (f.Page - 1) * f.PageSize
 }
 
+// Return the SQL expression used to count the total of records.
+// With a cursor the total is not needed, so we avoid the count(*) cost.
+func (f Filters) totalRecordsExpr() string {
+	if f.Cursor != "" {
+		return "0"
+	}
+
+	return "count(*) OVER()"
+}
+
+// Return the SQL condition (prefixed with AND) that skips the records
+// before the cursor and its arguments, or an empty string when there is
+// no cursor. column is the SQL expression of the sort column, idColumn
+// the qualified id column and argPos the position of the first placeholder.
+// The condition matches the "ORDER BY column direction, id ASC" clause
+// used in the List queries.
+func (f Filters) keysetCondition(column, idColumn string, argPos int) (string, []any, error) {
+	if f.Cursor == "" {
+		return "", nil, nil
+	}
+
+	value, id, err := f.decodeCursor()
+	if err != nil {
+		return "", nil, err
+	}
+
+	operator := ">"
+	if f.sortDirection() == "DESC" {
+		operator = "<"
+	}
+
+	condition := fmt.Sprintf(
+		"AND (%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND %[4]s > $%[5]d))",
+		column, operator, argPos, idColumn, argPos+1,
+	)
+
+	return condition, []any{value, id}, nil
+}
+
+// Decode the cursor and return the sort value, with the Go type matching
+// the sort column, and the id of the last record of the previous page.
+func (f Filters) decodeCursor() (any, int64, error) {
+	js, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var c cursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.Sort != f.Sort {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var value any
+	switch f.sortColumn() {
+	case "id", "price", "position":
+		var v int64
+		err = json.Unmarshal(c.Value, &v)
+		value = v
+	case "created_at":
+		var v time.Time
+		err = json.Unmarshal(c.Value, &v)
+		value = v
+	case "relevance":
+		var v float32
+		err = json.Unmarshal(c.Value, &v)
+		value = v
+	default:
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		value = v
+	}
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	return value, c.ID, nil
+}
+
+// Return an opaque cursor pointing after the record with the given
+// sort value and id.
+func (f Filters) encodeCursor(value any, id int64) (string, error) {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	js, err = json.Marshal(cursor{Sort: f.Sort, Value: js, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(js), nil
+}
+
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
@@ -62,10 +184,24 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	}
 }
 
+// Return the metadata for a page fetched with a cursor. The total of
+// records is not calculated, only the cursor for the next page.
+func calculateCursorMetadata(pageSize int, nextCursor string) Metadata {
+	return Metadata{
+		PageSize:   pageSize,
+		NextCursor: nextCursor,
+	}
+}
+
 func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be maximum of 100")
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+
+	if f.Cursor != "" && validator.PermittedValue(f.Sort, f.SortSafeList...) {
+		_, _, err := f.decodeCursor()
+		v.Check(err == nil, "cursor", "invalid cursor value or not valid for the sort value")
+	}
 }
@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+)
+
+// Sizes of the homepage sections
+const (
+	homeFeaturedLimit     = 12
+	homeNewestPerCategory = 4
+	homeCollectionsLimit  = 6
+)
+
+// struct to represent the content of the public homepage
+type Home struct {
+	Featured    []*Item       `json:"featured"`
+	Categories  []*Category   `json:"categories"` // with their newest items
+	Collections []*Collection `json:"collections"`
+}
+
+type HomeModel struct {
+	DB        *pgxpool.Pool
+	S3Manager filestorage.S3
+}
+
+// Return the featured items, the newest items per category and the newest
+// published collections. The queries are sent in a single batch, so the
+// homepage costs one round trip to the DB.
+func (m *HomeModel) Get(locale string) (*Home, error) {
+	featuredQuery := `
+		SELECT items.id, COALESCE(item_translations.name, items.name) AS name,
+			COALESCE(item_translations.description, items.description) AS description,
+			items.created_at, items.category_id, items.price, items.currency, items.availability,
+			COALESCE(item_attachments.key, '') AS key
+		FROM featured_items
+		INNER JOIN items ON items.id = featured_items.item_id
+		LEFT JOIN item_attachments ON items.id = item_attachments.item_id
+		LEFT JOIN item_translations
+			ON item_translations.item_id = items.id AND item_translations.locale = $1
+		WHERE items.deleted_at IS NULL
+		AND (featured_items.starts_at IS NULL OR featured_items.starts_at <= NOW())
+		AND (featured_items.ends_at IS NULL OR featured_items.ends_at > NOW())
+		ORDER BY featured_items.priority DESC, items.id ASC
+		LIMIT $2
+	`
+
+	newestQuery := `
+		SELECT categories.id, COALESCE(category_translations.name, categories.name) AS category_name,
+			newest.id, newest.name, newest.description, newest.created_at,
+			newest.price, newest.currency, newest.availability, newest.key
+		FROM categories
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $1
+		CROSS JOIN LATERAL (
+			SELECT items.id, COALESCE(item_translations.name, items.name) AS name,
+				COALESCE(item_translations.description, items.description) AS description,
+				items.created_at, items.price, items.currency, items.availability,
+				COALESCE(item_attachments.key, '') AS key
+			FROM items
+			LEFT JOIN item_attachments ON items.id = item_attachments.item_id
+			LEFT JOIN item_translations
+				ON item_translations.item_id = items.id AND item_translations.locale = $1
+			WHERE items.category_id = categories.id AND items.deleted_at IS NULL
+			ORDER BY items.created_at DESC, items.id DESC
+			LIMIT $2
+		) AS newest
+		WHERE categories.deleted_at IS NULL
+		ORDER BY category_name ASC, categories.id ASC, newest.created_at DESC, newest.id DESC
+	`
+
+	collectionsQuery := `
+		SELECT collections.id, collections.name, collections.description,
+			COALESCE(item_attachments.key, '') AS cover_key, collections.created_at,
+			(
+				SELECT COUNT(*) FROM collection_items
+				INNER JOIN items ON items.id = collection_items.item_id
+				WHERE collection_items.collection_id = collections.id AND items.deleted_at IS NULL
+			) AS items_count
+		FROM collections
+		LEFT JOIN item_attachments ON item_attachments.id = collections.cover_attachment_id
+		WHERE collections.published
+		ORDER BY collections.created_at DESC, collections.id DESC
+		LIMIT $1
+	`
+
+	batch := &pgx.Batch{}
+	batch.Queue(featuredQuery, locale, homeFeaturedLimit)
+	batch.Queue(newestQuery, locale, homeNewestPerCategory)
+	batch.Queue(collectionsQuery, homeCollectionsLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	results := m.DB.SendBatch(ctx, batch)
+	defer results.Close()
+
+	home := Home{
+		Featured:    []*Item{},
+		Categories:  []*Category{},
+		Collections: []*Collection{},
+	}
+
+	// featured items
+	rows, err := results.Query()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var item Item
+		err := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&item.Description,
+			&item.CreatedAt,
+			&item.CategoryID,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.ItemAttachment.Key,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		item.ImageURL = m.S3Manager.GetFileUrl(item.ItemAttachment.Key)
+
+		home.Featured = append(home.Featured, &item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// newest items per category, the rows are grouped by category
+	rows, err = results.Query()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var category Category
+		var item Item
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&item.ID,
+			&item.Name,
+			&item.Description,
+			&item.CreatedAt,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.ItemAttachment.Key,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		item.CategoryID = category.ID
+		item.CategoryName = category.Name
+		item.ImageURL = m.S3Manager.GetFileUrl(item.ItemAttachment.Key)
+
+		last := len(home.Categories) - 1
+		if last < 0 || home.Categories[last].ID != category.ID {
+			home.Categories = append(home.Categories, &category)
+			last++
+		}
+		home.Categories[last].Items = append(home.Categories[last].Items, &item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// published collections
+	rows, err = results.Query()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var collection Collection
+		var coverKey string
+		err := rows.Scan(
+			&collection.ID,
+			&collection.Name,
+			&collection.Description,
+			&coverKey,
+			&collection.CreatedAt,
+			&collection.ItemsCount,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		collection.CoverURL = m.S3Manager.GetFileUrl(coverKey)
+		collection.Published = true
+
+		home.Collections = append(home.Collections, &collection)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &home, nil
+}
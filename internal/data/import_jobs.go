@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status of an import job
+const (
+	ImportPending  = "pending"
+	ImportRunning  = "running"
+	ImportFinished = "finished"
+	ImportFailed   = "failed"
+)
+
+// struct to represent a catalog import running in the background
+type ImportJob struct {
+	ID            int64              `json:"id"`
+	Status        string             `json:"status"`
+	Filename      string             `json:"filename"`
+	TotalRows     int32              `json:"total_rows"`
+	ProcessedRows int32              `json:"processed_rows"`
+	Report        []*ImportRowResult `json:"report"`
+	Error         string             `json:"error,omitempty"` // why the whole import failed
+	AdminUserID   *int64             `json:"admin_user_id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	FinishedAt    *time.Time         `json:"finished_at"`
+}
+
+// The outcome of the import of one row of the manifest
+type ImportRowResult struct {
+	Row    int               `json:"row"` // line of the manifest, the header is line 1
+	Status string            `json:"status"`
+	ItemID int64             `json:"item_id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type ImportJobModel struct {
+	DB *pgxpool.Pool
+}
+
+func (m *ImportJobModel) Insert(job *ImportJob) error {
+	query := `
+		INSERT INTO import_jobs (status, filename, total_rows, admin_user_id)
+		VALUES ($1, $2, $3, NULLIF($4::bigint, 0))
+		RETURNING id, created_at
+	`
+	if job.Report == nil {
+		job.Report = []*ImportRowResult{}
+	}
+
+	var adminUserID int64
+	if job.AdminUserID != nil {
+		adminUserID = *job.AdminUserID
+	}
+
+	args := []any{job.Status, job.Filename, job.TotalRows, adminUserID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRow(ctx, query, args...).Scan(&job.ID, &job.CreatedAt)
+}
+
+func (m *ImportJobModel) Get(id int64) (*ImportJob, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, status, filename, total_rows, processed_rows, report, error,
+			admin_user_id, created_at, finished_at
+		FROM import_jobs
+		WHERE id = $1
+	`
+
+	var job ImportJob
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, id).Scan(
+		&job.ID,
+		&job.Status,
+		&job.Filename,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&job.Report,
+		&job.Error,
+		&job.AdminUserID,
+		&job.CreatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+// Save the progress of the job, the finish time is set once the job
+// is finished or failed
+func (m *ImportJobModel) Update(job *ImportJob) error {
+	query := `
+		UPDATE import_jobs
+		SET status = $1, total_rows = $2, processed_rows = $3, report = $4, error = $5,
+			finished_at = CASE WHEN $1 IN ('finished', 'failed') THEN NOW() END
+		WHERE id = $6
+		RETURNING finished_at
+	`
+
+	args := []any{job.Status, job.TotalRows, job.ProcessedRows, job.Report, job.Error, job.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&job.FinishedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -22,6 +23,15 @@ type ItemAttachmentModel struct {
 }
 
 func (m *ItemAttachmentModel) Insert(itemAttachment *ItemAttachment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertItemAttachment(ctx, m.DB, itemAttachment)
+}
+
+func insertItemAttachment(ctx context.Context, db interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, itemAttachment *ItemAttachment) error {
 	query := `
 		INSERT INTO item_attachments (key, filename, content_type, byte_size, item_id)
 		VALUES($1, $2, $3, $4, $5)
@@ -36,10 +46,7 @@ func (m *ItemAttachmentModel) Insert(itemAttachment *ItemAttachment) error {
 		itemAttachment.ItemID,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	return m.DB.QueryRow(ctx, query, args...).Scan(
+	return db.QueryRow(ctx, query, args...).Scan(
 		&itemAttachment.ID,
 		&itemAttachment.CreatedAt,
 	)
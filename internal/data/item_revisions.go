@@ -0,0 +1,230 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SQL expression to build the snapshot of a row of the items table
+const itemSnapshotExpr = `jsonb_build_object(
+	'name', items.name, 'description', items.description, 'category_id', items.category_id,
+	'price', items.price, 'currency', items.currency, 'availability', items.availability,
+	'stock', items.stock, 'attributes', items.attributes, 'tags', items.tags
+)`
+
+// The editable fields of an item at a given version
+type ItemSnapshot struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	CategoryID   int64          `json:"category_id"`
+	Price        int64          `json:"price"`
+	Currency     string         `json:"currency"`
+	Availability string         `json:"availability"`
+	Stock        int32          `json:"stock"`
+	Attributes   map[string]any `json:"attributes"`
+	Tags         []string       `json:"tags"` // nil in the revisions saved before the items had tags
+}
+
+// struct to represent the state of an item before an update.
+// Changes are the fields modified by the update that replaced this version.
+type ItemRevision struct {
+	ID          int64         `json:"id"`
+	ItemID      int64         `json:"item_id"`
+	Version     int32         `json:"version"`
+	Snapshot    ItemSnapshot  `json:"snapshot"`
+	Changes     []FieldChange `json:"changes"`
+	AdminUserID *int64        `json:"admin_user_id"` // who made the update, nil if unknown
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// A field modified between two versions of an item
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type ItemRevisionModel struct {
+	DB *pgxpool.Pool
+}
+
+// Save the current state of the item as a revision made by the given admin
+// user (0 when unknown). Nothing is saved when the version does not match.
+func insertItemRevision(ctx context.Context, tx pgx.Tx, itemID int64, version int32, adminUserID int64) error {
+	query := `
+		INSERT INTO item_revisions (item_id, version, snapshot, admin_user_id)
+		SELECT items.id, items.version, ` + itemSnapshotExpr + `, NULLIF($3::bigint, 0)
+		FROM items
+		WHERE items.id = $1 AND items.version = $2 AND items.deleted_at IS NULL
+	`
+
+	_, err := tx.Exec(ctx, query, itemID, version, adminUserID)
+	if err != nil {
+		switch {
+		// another update already saved this version
+		case err.Error() == `ERROR: duplicate key value violates unique constraint "item_revisions_item_id_version_key" (SQLSTATE 23505)`:
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return the revisions of the item, the newest first, with the changes
+// of every revision computed against the following version of the item.
+func (m *ItemRevisionModel) GetAllForItem(itemID int64) ([]*ItemRevision, error) {
+	if itemID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	queryCurrent := `
+		SELECT ` + itemSnapshotExpr + `
+		FROM items
+		WHERE items.id = $1 AND items.deleted_at IS NULL
+	`
+
+	query := `
+		SELECT id, item_id, version, snapshot, admin_user_id, created_at
+		FROM item_revisions
+		WHERE item_id = $1
+		ORDER BY version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var next ItemSnapshot
+
+	err := m.DB.QueryRow(ctx, queryCurrent, itemID).Scan(&next)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	rows, err := m.DB.Query(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*ItemRevision{}
+	for rows.Next() {
+		var revision ItemRevision
+		err := rows.Scan(
+			&revision.ID,
+			&revision.ItemID,
+			&revision.Version,
+			&revision.Snapshot,
+			&revision.AdminUserID,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revision.Changes = revision.Snapshot.diff(next)
+		next = revision.Snapshot
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Return the revision of the item with the given version
+func (m *ItemRevisionModel) Get(itemID int64, version int32) (*ItemRevision, error) {
+	if itemID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, item_id, version, snapshot, admin_user_id, created_at
+		FROM item_revisions
+		WHERE item_id = $1 AND version = $2
+	`
+
+	var revision ItemRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, itemID, version).Scan(
+		&revision.ID,
+		&revision.ItemID,
+		&revision.Version,
+		&revision.Snapshot,
+		&revision.AdminUserID,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// Set the editable fields of the item to the values of the snapshot
+func (s ItemSnapshot) Apply(item *Item) {
+	item.Name = s.Name
+	item.Description = s.Description
+	item.CategoryID = s.CategoryID
+	item.Price = s.Price
+	item.Currency = s.Currency
+	item.Availability = s.Availability
+	item.Stock = s.Stock
+	item.Attributes = s.Attributes
+	item.Tags = s.Tags
+}
+
+// Return the fields that differ from the next snapshot
+func (s ItemSnapshot) diff(next ItemSnapshot) []FieldChange {
+	fields := []FieldChange{
+		{Field: "name", From: s.Name, To: next.Name},
+		{Field: "description", From: s.Description, To: next.Description},
+		{Field: "category_id", From: s.CategoryID, To: next.CategoryID},
+		{Field: "price", From: s.Price, To: next.Price},
+		{Field: "currency", From: s.Currency, To: next.Currency},
+		{Field: "availability", From: s.Availability, To: next.Availability},
+		{Field: "stock", From: s.Stock, To: next.Stock},
+		{Field: "attributes", From: s.Attributes, To: next.Attributes},
+		{Field: "tags", From: s.tags(), To: next.tags()},
+	}
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.From, field.To) {
+			changes = append(changes, field)
+		}
+	}
+
+	return changes
+}
+
+// Return the tags of the snapshot, the revisions without tags had none
+func (s ItemSnapshot) tags() []string {
+	if s.Tags == nil {
+		return []string{}
+	}
+
+	return s.Tags
+}
@@ -0,0 +1,274 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+var (
+	ErrDuplicateSKU      = errors.New("duplicate sku")
+	ErrInvalidAttachment = errors.New("invalid attachment")
+)
+
+// struct to represent a variant (size, color, material) of an Item
+type ItemVariant struct {
+	ID               int64     `json:"id"`
+	ItemID           int64     `json:"item_id"`
+	SKU              string    `json:"sku"`
+	Size             string    `json:"size,omitempty"`
+	Color            string    `json:"color,omitempty"`
+	Material         string    `json:"material,omitempty"`
+	Price            *int64    `json:"price,omitempty"` // overrides the item price when set
+	Stock            int32     `json:"stock"`
+	ItemAttachmentID *int64    `json:"item_attachment_id,omitempty"`
+	ImageURL         string    `json:"image_url,omitempty"` // extracted from join with item_attachments table
+	CreatedAt        time.Time `json:"created_at"`
+	Version          int32     `json:"version"`
+}
+
+type ItemVariantModel struct {
+	DB        *pgxpool.Pool
+	S3Manager filestorage.S3
+}
+
+// Insert in DB a new ItemVariant based on the variant given
+func (m *ItemVariantModel) Insert(variant *ItemVariant) error {
+	query := `
+		INSERT INTO item_variants (item_id, sku, size, color, material, price, stock, item_attachment_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, version
+	`
+	args := []any{
+		variant.ItemID,
+		variant.SKU,
+		variant.Size,
+		variant.Color,
+		variant.Material,
+		variant.Price,
+		variant.Stock,
+		variant.ItemAttachmentID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, args...).Scan(
+		&variant.ID,
+		&variant.CreatedAt,
+		&variant.Version,
+	)
+	if err != nil {
+		return variantError(err)
+	}
+
+	return nil
+}
+
+// Return a single variant of the given item
+func (m *ItemVariantModel) Get(itemID, id int64) (*ItemVariant, error) {
+	if itemID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT
+			item_variants.id, item_variants.item_id, item_variants.sku, item_variants.size,
+			item_variants.color, item_variants.material, item_variants.price, item_variants.stock,
+			item_variants.item_attachment_id, COALESCE(item_attachments.key, '') AS key,
+			item_variants.created_at, item_variants.version
+		FROM item_variants
+		INNER JOIN items ON items.id = item_variants.item_id AND items.deleted_at IS NULL
+		LEFT JOIN item_attachments ON item_attachments.id = item_variants.item_attachment_id
+		WHERE item_variants.item_id = $1 AND item_variants.id = $2
+	`
+
+	var variant ItemVariant
+	var key string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, itemID, id).Scan(
+		&variant.ID,
+		&variant.ItemID,
+		&variant.SKU,
+		&variant.Size,
+		&variant.Color,
+		&variant.Material,
+		&variant.Price,
+		&variant.Stock,
+		&variant.ItemAttachmentID,
+		&key,
+		&variant.CreatedAt,
+		&variant.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	variant.ImageURL = m.S3Manager.GetFileUrl(key)
+
+	return &variant, nil
+}
+
+// Return all the variants of the given item
+func (m *ItemVariantModel) GetAllForItem(itemID int64) ([]*ItemVariant, error) {
+	query := `
+		SELECT
+			item_variants.id, item_variants.item_id, item_variants.sku, item_variants.size,
+			item_variants.color, item_variants.material, item_variants.price, item_variants.stock,
+			item_variants.item_attachment_id, COALESCE(item_attachments.key, '') AS key,
+			item_variants.created_at, item_variants.version
+		FROM item_variants
+		INNER JOIN items ON items.id = item_variants.item_id AND items.deleted_at IS NULL
+		LEFT JOIN item_attachments ON item_attachments.id = item_variants.item_attachment_id
+		WHERE item_variants.item_id = $1
+		ORDER BY item_variants.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*ItemVariant{}
+
+	for rows.Next() {
+		var variant ItemVariant
+		var key string
+
+		err := rows.Scan(
+			&variant.ID,
+			&variant.ItemID,
+			&variant.SKU,
+			&variant.Size,
+			&variant.Color,
+			&variant.Material,
+			&variant.Price,
+			&variant.Stock,
+			&variant.ItemAttachmentID,
+			&key,
+			&variant.CreatedAt,
+			&variant.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		variant.ImageURL = m.S3Manager.GetFileUrl(key)
+
+		variants = append(variants, &variant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (m *ItemVariantModel) Update(variant *ItemVariant) error {
+	query := `
+		UPDATE item_variants
+		SET sku = $1, size = $2, color = $3, material = $4, price = $5, stock = $6,
+			item_attachment_id = $7, version = item_variants.version + 1
+		FROM items
+		WHERE item_variants.item_id = $8 AND item_variants.id = $9 AND item_variants.version = $10
+		AND items.id = item_variants.item_id AND items.deleted_at IS NULL
+		RETURNING item_variants.version
+	`
+
+	args := []any{
+		variant.SKU,
+		variant.Size,
+		variant.Color,
+		variant.Material,
+		variant.Price,
+		variant.Stock,
+		variant.ItemAttachmentID,
+		variant.ItemID,
+		variant.ID,
+		variant.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&variant.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return variantError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *ItemVariantModel) Delete(itemID, id int64) error {
+	if itemID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM item_variants
+		USING items
+		WHERE item_variants.item_id = $1 AND item_variants.id = $2
+		AND items.id = item_variants.item_id AND items.deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, itemID, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Translate the constraint errors of the item_variants table
+func variantError(err error) error {
+	switch {
+	case err.Error() == `ERROR: duplicate key value violates unique constraint "item_variants_sku_key" (SQLSTATE 23505)`:
+		return ErrDuplicateSKU
+	case err.Error() == `ERROR: insert or update on table "item_variants" violates foreign key constraint "item_variants_item_attachment_fkey" (SQLSTATE 23503)`:
+		return ErrInvalidAttachment
+	default:
+		return err
+	}
+}
+
+func ValidateItemVariant(v *validator.Validator, variant *ItemVariant) {
+	v.Check(variant.SKU != "", "sku", "must be provided")
+	v.Check(len(variant.SKU) <= 64, "sku", "must not be more than 64 bytes long")
+
+	v.Check(len(variant.Size) <= 100, "size", "must not be more than 100 bytes long")
+	v.Check(len(variant.Color) <= 100, "color", "must not be more than 100 bytes long")
+	v.Check(len(variant.Material) <= 100, "material", "must not be more than 100 bytes long")
+
+	if variant.Price != nil {
+		v.Check(*variant.Price >= 0, "price", "must be zero or greater")
+	}
+	v.Check(variant.Stock >= 0, "stock", "must be zero or greater")
+}
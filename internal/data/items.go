@@ -2,7 +2,6 @@ package data
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"time"
@@ -20,9 +19,125 @@ type Item struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	CategoryID     int64          `json:"category_id"`
 	Version        int32          `json:"version"`
+	Price          int64          `json:"price"` // in minor units of the currency, e.g. cents
+	Currency       string         `json:"currency"`
+	Availability   string         `json:"availability"`
+	Stock          int32          `json:"stock"`
+	Position       int32          `json:"position"`                // manual order of the item within its category
+	Attributes     map[string]any `json:"attributes"`              // validated against the attribute schema of the category
+	Tags           []string       `json:"tags"`                    // free labels used to filter the items, e.g. "handmade"
 	CategoryName   string         `json:"category_name,omitempty"` // extracted from join with categories table
+	Headline       string         `json:"headline,omitempty"`      // description snippet with the search terms highlighted
+	Relevance      float32        `json:"-"`                       // search rank, used to sort by relevance
 	ImageURL       string         `json:"image_url,omitempty"`     // extracted from join with item_attachments table
 	ItemAttachment ItemAttachment `json:"item_attachment,omitempty"`
+	Variants       []*ItemVariant `json:"variants,omitempty"`
+	// admin users who created and last updated the item, nil when unknown
+	CreatedBy *int64 `json:"created_by,omitempty"`
+	UpdatedBy *int64 `json:"updated_by,omitempty"`
+}
+
+// Default values for the commercial fields of a new item
+const (
+	DefaultCurrency     = "USD"
+	DefaultAvailability = "available"
+)
+
+// Permitted values for the availability of an item
+var ItemAvailabilities = []string{"available", "sold", "made-to-order"}
+
+// Lower bounds, in minor units, of the price ranges used for the price facet
+var priceRangeBounds = []int64{0, 1_000, 5_000, 10_000, 50_000}
+
+// Filters of the items listing, a zero value means no filter
+type ItemSearch struct {
+	Name         string
+	CategoryID   int
+	MinPrice     int64
+	MaxPrice     int64
+	Availability string
+	Attributes   map[string]string // e.g. ?attr.material=wool
+	CollectionID int
+	Tags         []string // the items must have all the tags
+	Locale       string   // language of the returned content, not a filter
+}
+
+// Return the SQL conditions of the search, using the placeholders $1 to $8.
+// Use len(args()) to know the position of the next placeholder.
+func (s ItemSearch) conditions() string {
+	return `
+		items.deleted_at IS NULL
+		AND (items.search_vector @@ plainto_tsquery('es_unaccent', $1) OR $1 = '')
+		AND (items.category_id = $2 OR $2 = 0)
+		AND (items.price >= $3 OR $3 = 0)
+		AND (items.price <= $4 OR $4 = 0)
+		AND (items.availability = $5 OR $5 = '')
+		AND NOT EXISTS (
+			SELECT 1 FROM jsonb_each_text($6::jsonb) AS attr
+			WHERE items.attributes ->> attr.key IS DISTINCT FROM attr.value
+		)
+		AND (EXISTS (
+			SELECT 1 FROM collection_items
+			WHERE collection_items.collection_id = $7 AND collection_items.item_id = items.id
+		) OR $7 = 0)
+		AND items.tags @> $8::text[]
+	`
+}
+
+// Return the arguments for the placeholders used in conditions
+func (s ItemSearch) args() []any {
+	attributes := s.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	// an empty array matches every item
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return []any{s.Name, s.CategoryID, s.MinPrice, s.MaxPrice, s.Availability, attributes, s.CollectionID, tags}
+}
+
+// Return the locale of the search, the default locale when not set
+func (s ItemSearch) locale() string {
+	if s.Locale == "" {
+		return DefaultLocale
+	}
+
+	return s.Locale
+}
+
+// Counts of items per value of a field, used to render filters with counts
+type Facets struct {
+	Categories  []CategoryFacet   `json:"categories"`
+	Tags        []TagFacet        `json:"tags"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+	Years       []YearFacet       `json:"years"`
+}
+
+// MaxPrice is omitted for the last range, which has no upper bound
+type PriceRangeFacet struct {
+	MinPrice int64 `json:"min_price"`
+	MaxPrice int64 `json:"max_price,omitempty"`
+	Count    int   `json:"count"`
+}
+
+type CategoryFacet struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type YearFacet struct {
+	Year  int `json:"year"`
+	Count int `json:"count"`
 }
 
 type ItemModel struct {
@@ -30,42 +145,111 @@ type ItemModel struct {
 	S3Manager filestorage.S3
 }
 
-// Insert in DB a new Item based on the item struct given
-func (m *ItemModel) Insert(item *Item) error {
+// Insert in DB a new Item based on the item struct given, created by the
+// given admin user (0 when unknown)
+func (m *ItemModel) Insert(item *Item, adminUserID int64) error {
+	return insertItem(context.Background(), m.DB, item, adminUserID)
+}
+
+// Insert the item and its attachment in a single transaction, so there is
+// no item left without its image when the attachment fails
+func (m *ItemModel) InsertWithAttachment(item *Item, attachment *ItemAttachment, adminUserID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	err = insertItem(ctx, tx, item, adminUserID)
+	if err != nil {
+		return err
+	}
+
+	attachment.ItemID = item.ID
+
+	err = insertItemAttachment(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func insertItem(ctx context.Context, db interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, item *Item, adminUserID int64) error {
 	query := `
-		INSERT INTO items (name, description, category_id)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, version
+		INSERT INTO items (name, description, category_id, price, currency, availability, stock, attributes, tags,
+			position, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $10, (
+			-- new items are placed first in the category
+			SELECT COALESCE(MIN(position), 1) - 1 FROM items WHERE category_id = $3
+		), NULLIF($9, 0), NULLIF($9, 0))
+		RETURNING id, created_at, version, position, created_by, updated_by
 	`
+	if item.Attributes == nil {
+		item.Attributes = map[string]any{}
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+
 	args := []interface{}{
 		item.Name,
 		item.Description,
 		item.CategoryID,
+		item.Price,
+		item.Currency,
+		item.Availability,
+		item.Stock,
+		item.Attributes,
+		adminUserID,
+		item.Tags,
 	}
 
-	return m.DB.QueryRow(context.Background(), query, args...).Scan(
+	return db.QueryRow(ctx, query, args...).Scan(
 		&item.ID,
 		&item.CreatedAt,
 		&item.Version,
+		&item.Position,
+		&item.CreatedBy,
+		&item.UpdatedBy,
 	)
 }
 
-// Return a single item based on the ID given
+// Return a single item based on the ID given, with the content in the default locale
 func (m *ItemModel) Get(id int64) (*Item, error) {
+	return m.GetLocalized(id, DefaultLocale)
+}
+
+// Return a single item based on the ID given, with the name and description
+// in the given locale, falling back to the default locale when there is no translation
+func (m *ItemModel) GetLocalized(id int64, locale string) (*Item, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
 		SELECT
-			items.id, items.name, items.description, items.created_at, items.version,
-			items.category_id, categories.name AS category_name,
+			items.id, COALESCE(item_translations.name, items.name) AS name,
+			COALESCE(item_translations.description, items.description) AS description,
+			items.created_at, items.version,
+			items.price, items.currency, items.availability, items.stock, items.position,
+			items.attributes, items.tags, items.created_by, items.updated_by, items.category_id, COALESCE(category_translations.name, categories.name) AS category_name,
 			COALESCE(item_attachments.filename, '') as filename,
 			COALESCE(item_attachments.key, '') as key
 		FROM items
 		INNER JOIN categories ON categories.id = items.category_id
 		LEFT JOIN item_attachments ON items.id = item_attachments.item_id
-		WHERE items.id = $1
+		LEFT JOIN item_translations
+			ON item_translations.item_id = items.id AND item_translations.locale = $2
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $2
+		WHERE items.id = $1 AND items.deleted_at IS NULL
 	`
 
 	var item Item
@@ -73,12 +257,21 @@ func (m *ItemModel) Get(id int64) (*Item, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRow(ctx, query, id).Scan(
+	err := m.DB.QueryRow(ctx, query, id, locale).Scan(
 		&item.ID,
 		&item.Name,
 		&item.Description,
 		&item.CreatedAt,
 		&item.Version,
+		&item.Price,
+		&item.Currency,
+		&item.Availability,
+		&item.Stock,
+		&item.Position,
+		&item.Attributes,
+		&item.Tags,
+		&item.CreatedBy,
+		&item.UpdatedBy,
 		&item.CategoryID,
 		&item.CategoryName,
 		&item.ItemAttachment.Filename,
@@ -95,68 +288,150 @@ func (m *ItemModel) Get(id int64) (*Item, error) {
 	url := m.S3Manager.GetFileUrl(item.ItemAttachment.Key)
 	item.ImageURL = url
 
+	variants := ItemVariantModel{DB: m.DB, S3Manager: m.S3Manager}
+	item.Variants, err = variants.GetAllForItem(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &item, nil
 }
 
-func (m *ItemModel) Update(item *Item) error {
+// Update the item, saving its previous state as a revision made by the
+// given admin user (0 when unknown) in the same transaction.
+func (m *ItemModel) Update(item *Item, adminUserID int64) error {
 	query := `
 		UPDATE items
-		SET name = $1, description = $2, category_id = $3, version = version + 1
-		WHERE id = $4 AND version = $5
-		RETURNING version
+		SET name = $1, description = $2, category_id = $3, price = $4, currency = $5,
+			availability = $6, stock = $7, attributes = $8, tags = $12, updated_by = NULLIF($11, 0),
+			version = version + 1,
+			-- an item moved to another category is placed first in it
+			position = CASE WHEN category_id = $3 THEN position
+				ELSE (SELECT COALESCE(MIN(position), 1) - 1 FROM items WHERE category_id = $3)
+			END
+		WHERE id = $9 AND version = $10 AND deleted_at IS NULL
+		RETURNING version, position, updated_by
 	`
+	if item.Attributes == nil {
+		item.Attributes = map[string]any{}
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
 
 	args := []any{
 		item.Name,
 		item.Description,
 		item.CategoryID,
+		item.Price,
+		item.Currency,
+		item.Availability,
+		item.Stock,
+		item.Attributes,
 		item.ID,
 		item.Version,
+		adminUserID,
+		item.Tags,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRow(ctx, query, args...).Scan(&item.Version)
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	err = insertItemRevision(ctx, tx, item.ID, item.Version, adminUserID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&item.Version, &item.Position, &item.UpdatedBy)
 	if err != nil {
 		switch {
-		case errors.Is(err, sql.ErrNoRows):
+		case errors.Is(err, pgx.ErrNoRows):
 			return ErrEditConflict
 		default:
 			return err
 		}
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
+// Move the item to the trash. The item and its files are permanently
+// deleted by the retention job of the TrashModel.
 func (m *ItemModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-		DELETE FROM items
-		WHERE id = $1
+		UPDATE items
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	// SQL query to find the key of the ItemAttachment attached to the Item
-	queryAttachment := `
-		SELECT item_attachments.key
-		FROM item_attachments
-		WHERE item_id = $1
-		LIMIT 1
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected := result.RowsAffected()
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Restore the item from the trash. ErrTrashedCategory is returned when
+// its category is in the trash, the category must be restored first.
+func (m *ItemModel) Restore(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE items
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		AND EXISTS (
+			SELECT 1 FROM categories
+			WHERE categories.id = items.category_id AND categories.deleted_at IS NULL
+		)
+	`
+
+	// SQL query to know if the item was not restored because of its category
+	queryCategory := `
+		SELECT categories.deleted_at IS NOT NULL
+		FROM items
+		INNER JOIN categories ON categories.id = items.category_id
+		WHERE items.id = $1 AND items.deleted_at IS NOT NULL
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	attachment := ItemAttachment{}
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
 
-	// query queryAttachment execution
-	err := m.DB.QueryRow(ctx, queryAttachment, id).Scan(
-		&attachment.Key,
-	)
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+
+	var categoryTrashed bool
+
+	err = m.DB.QueryRow(ctx, queryCategory, id).Scan(&categoryTrashed)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -166,48 +441,69 @@ func (m *ItemModel) Delete(id int64) error {
 		}
 	}
 
-	// Delete from S3 the file attached to the Item
-	err = m.S3Manager.DeleteFile(attachment.Key)
-	if err != nil {
-		return err
+	if categoryTrashed {
+		return ErrTrashedCategory
 	}
 
-	result, err := m.DB.Exec(ctx, query, id)
-	if err != nil {
-		return err
+	return ErrRecordNotFound
+}
+
+func (m *ItemModel) List(search ItemSearch, filters Filters) ([]*Item, Metadata, error) {
+	// rank of the item for the search terms, weighted by name, description and category name
+	relevance := "ts_rank(items.search_vector, plainto_tsquery('es_unaccent', $1))"
+
+	sortColumn := "items." + filters.sortColumn()
+	switch filters.sortColumn() {
+	case "relevance":
+		sortColumn = relevance
+	case "name":
+		// sorted by the returned name, the one saved in the cursor
+		sortColumn = "COALESCE(item_translations.name, items.name)"
 	}
 
-	rowsAffected := result.RowsAffected()
+	args := search.args()
+	n := len(args)
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	keyset, keysetArgs, err := filters.keysetCondition(sortColumn, "items.id", n+4)
+	if err != nil {
+		return nil, Metadata{}, err
 	}
 
-	return nil
-}
-
-func (m *ItemModel) List(name string, categoryID int, filters Filters) ([]*Item, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT
-			count(*) OVER(), items.id, items.name, items.description, items.created_at,
-			items.category_id, items.version, categories.name AS category_name,
+			%s, items.id, COALESCE(item_translations.name, items.name) AS name,
+			COALESCE(item_translations.description, items.description) AS description,
+			items.created_at, items.category_id, items.version, items.price, items.currency,
+			items.availability, items.stock, items.position, items.attributes, items.tags,
+			COALESCE(category_translations.name, categories.name) AS category_name,
 			COALESCE(item_attachments.filename, '') AS filename,
-			COALESCE(item_attachments.key, '') AS key
+			COALESCE(item_attachments.key, '') AS key,
+			%s AS relevance,
+			CASE WHEN $1 = '' THEN ''
+				ELSE ts_headline('es_unaccent', items.description, plainto_tsquery('es_unaccent', $1))
+			END AS headline
 		FROM items
 		INNER JOIN categories ON categories.id = items.category_id
 		LEFT JOIN item_attachments on items.id = item_attachments.item_id
-		WHERE (to_tsvector('simple', items.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (items.category_id = $2 OR $2 = 0)
-		ORDER by %s %s, id ASC
-		LIMIT $3
-		OFFSET $4
-	`, filters.sortColumn(), filters.sortDirection())
+		LEFT JOIN item_translations
+			ON item_translations.item_id = items.id AND item_translations.locale = $%[7]d
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $%[7]d
+		WHERE %[3]s
+		%[4]s
+		ORDER by %[5]s %[6]s, items.id ASC
+		LIMIT $%[8]d
+		OFFSET $%[9]d
+	`, filters.totalRecordsExpr(), relevance, search.conditions(), keyset,
+		sortColumn, filters.sortDirection(), n+1, n+2, n+3)
 
 	// 3 seconds timeout for quering the DB
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{name, categoryID, filters.limit(), filters.offset()}
+	// one extra record is fetched to know if there is a next page
+	args = append(args, search.locale(), filters.limit()+1, filters.offset())
+	args = append(args, keysetArgs...)
 
 	rows, err := m.DB.Query(ctx, query, args...)
 	if err != nil {
@@ -229,9 +525,18 @@ func (m *ItemModel) List(name string, categoryID int, filters Filters) ([]*Item,
 			&item.CreatedAt,
 			&item.CategoryID,
 			&item.Version,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.Stock,
+			&item.Position,
+			&item.Attributes,
+			&item.Tags,
 			&item.CategoryName,
 			&item.ItemAttachment.Filename,
 			&item.ItemAttachment.Key,
+			&item.Relevance,
+			&item.Headline,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -247,17 +552,169 @@ func (m *ItemModel) List(name string, categoryID int, filters Filters) ([]*Item,
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	nextCursor := ""
+	if len(items) > filters.limit() {
+		items = items[:filters.limit()]
+		last := items[len(items)-1]
+
+		nextCursor, err = filters.encodeCursor(last.sortValue(filters.sortColumn()), last.ID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	var metadata Metadata
+	if filters.Cursor != "" {
+		metadata = calculateCursorMetadata(filters.PageSize, nextCursor)
+	} else {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		metadata.NextCursor = nextCursor
+	}
 
 	return items, metadata, nil
 }
 
+// Return the facets, counts of items per category, tag, price range and
+// publication year, for the items matching the same search of List.
+func (m *ItemModel) Facets(search ItemSearch) (*Facets, error) {
+	query := fmt.Sprintf(`
+		WITH matches AS (
+			SELECT items.category_id,
+				COALESCE(category_translations.name, categories.name::text) AS category_name,
+				items.tags,
+				width_bucket(items.price, $%d::bigint[]) AS price_range,
+				EXTRACT(YEAR FROM items.created_at)::integer AS year
+			FROM items
+			INNER JOIN categories ON categories.id = items.category_id
+			LEFT JOIN category_translations
+				ON category_translations.category_id = categories.id AND category_translations.locale = $%[2]d
+			WHERE %[3]s
+		)
+		SELECT 'category' AS facet, category_id AS value, category_name AS label, count(*)
+		FROM matches
+		GROUP BY category_id, category_name
+		UNION ALL
+		SELECT 'tag' AS facet, 0 AS value, tag AS label, count(*)
+		FROM matches, unnest(matches.tags) AS tag
+		GROUP BY tag
+		UNION ALL
+		SELECT 'price_range' AS facet, price_range AS value, price_range::text AS label, count(*)
+		FROM matches
+		GROUP BY price_range
+		UNION ALL
+		SELECT 'year' AS facet, year AS value, year::text AS label, count(*)
+		FROM matches
+		GROUP BY year
+		ORDER BY facet, value, label
+	`, len(search.args())+1, len(search.args())+2, search.conditions())
+
+	// 3 seconds timeout for quering the DB
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := append(search.args(), priceRangeBounds, search.locale())
+
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := Facets{
+		Categories:  []CategoryFacet{},
+		Tags:        []TagFacet{},
+		PriceRanges: []PriceRangeFacet{},
+		Years:       []YearFacet{},
+	}
+
+	for rows.Next() {
+		var (
+			facet string
+			value int64
+			label string
+			count int
+		)
+
+		err := rows.Scan(&facet, &value, &label, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		switch facet {
+		case "category":
+			facets.Categories = append(facets.Categories, CategoryFacet{ID: value, Name: label, Count: count})
+		case "tag":
+			facets.Tags = append(facets.Tags, TagFacet{Tag: label, Count: count})
+		case "price_range":
+			// width_bucket returns the 1-based position of the range
+			priceRange := PriceRangeFacet{MinPrice: priceRangeBounds[value-1], Count: count}
+			if int(value) < len(priceRangeBounds) {
+				priceRange.MaxPrice = priceRangeBounds[value] - 1
+			}
+			facets.PriceRanges = append(facets.PriceRanges, priceRange)
+		case "year":
+			facets.Years = append(facets.Years, YearFacet{Year: int(value), Count: count})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &facets, nil
+}
+
+// Return the value of the given sort column, used to build the cursor
+func (i *Item) sortValue(column string) any {
+	switch column {
+	case "name":
+		return i.Name
+	case "created_at":
+		return i.CreatedAt
+	case "price":
+		return i.Price
+	case "position":
+		return i.Position
+	case "relevance":
+		return i.Relevance
+	default:
+		return i.ID
+	}
+}
+
 func ValidateItem(v *validator.Validator, item *Item) {
 	v.Check(item.Name != "", "name", "must be provided")
 	v.Check(len(item.Name) <= 100, "name", "must not be more than 100 bytes long")
 
 	v.Check(item.Description != "", "description", "must be provided")
 	v.Check(len(item.Description) <= 500, "description", "must not be more than 500 bytes long")
+
+	v.Check(item.Price >= 0, "price", "must be zero or greater")
+	v.Check(validator.Matches(item.Currency, validator.CurrencyRX), "currency", "must be a 3 letter ISO 4217 code")
+	v.Check(validator.PermittedValue(item.Availability, ItemAvailabilities...), "availability", "invalid availability value")
+	v.Check(item.Stock >= 0, "stock", "must be zero or greater")
+
+	ValidateTags(v, "tags", item.Tags)
+}
+
+// Check the tags of an item, key is the field reported in the errors
+func ValidateTags(v *validator.Validator, key string, tags []string) {
+	v.Check(len(tags) <= 20, key, "must not contain more than 20 tags")
+	v.Check(validator.Unique(tags), key, "must not contain duplicate values")
+
+	for _, tag := range tags {
+		v.Check(tag != "", key, "must not contain empty tags")
+		v.Check(len(tag) <= 50, key, "must not contain tags more than 50 bytes long")
+	}
+}
+
+func ValidateItemSearch(v *validator.Validator, search ItemSearch) {
+	v.Check(search.MinPrice >= 0, "min_price", "must be zero or greater")
+	v.Check(search.MaxPrice >= 0, "max_price", "must be zero or greater")
+	v.Check(search.MaxPrice == 0 || search.MaxPrice >= search.MinPrice, "max_price", "must be greater than min_price")
+
+	if search.Availability != "" {
+		v.Check(validator.PermittedValue(search.Availability, ItemAvailabilities...), "availability", "invalid availability value")
+	}
 }
 
 func ValidateItemCategoryID(v *validator.Validator, item *Item) {
@@ -0,0 +1,345 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// Actions of the bulk operations on items
+const (
+	BulkMoveItems       = "move"
+	BulkAddTags         = "add_tags"
+	BulkRemoveTags      = "remove_tags"
+	BulkSetAvailability = "set_availability"
+	BulkDeleteItems     = "delete"
+)
+
+// Status of an item in the results of a bulk operation
+const (
+	bulkStatusOK       = "ok"
+	bulkStatusNotFound = "not_found"
+	bulkStatusConflict = "edit_conflict"
+	bulkStatusInvalid  = "invalid"
+)
+
+// Maximum number of items of a bulk operation
+const MaxBulkItems = 500
+
+// Permitted values for the action of a bulk operation
+var BulkItemActions = []string{BulkMoveItems, BulkAddTags, BulkRemoveTags, BulkSetAvailability, BulkDeleteItems}
+
+var (
+	ErrBulkItemsFailed   = errors.New("bulk operation failed for some items")
+	ErrTooManyBulkItems  = errors.New("too many items for a bulk operation")
+	ErrInvalidBulkTarget = errors.New("invalid target category")
+)
+
+// The action to apply to every item of a bulk operation
+type BulkItemAction struct {
+	Action       string   `json:"action"`
+	CategoryID   int64    `json:"category_id"`  // for the move action
+	Tags         []string `json:"tags"`         // for the add_tags and remove_tags actions
+	Availability string   `json:"availability"` // for the set_availability action
+}
+
+// An item of a bulk operation, a zero Version skips the optimistic locking check
+type BulkItemTarget struct {
+	ID      int64 `json:"id"`
+	Version int32 `json:"version"`
+}
+
+// The outcome of a bulk operation for one item
+type BulkItemResult struct {
+	ID      int64             `json:"id"`
+	Status  string            `json:"status"` // ok, not_found, edit_conflict or invalid
+	Version int32             `json:"version,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// Apply the action to the given items, or to the items matching the search
+// when search is not nil, in a single transaction. The changes are committed
+// only when the action succeeds for every item, otherwise the results report
+// the failing items and ErrEditConflict or ErrBulkItemsFailed is returned.
+func (m *ItemModel) Bulk(action BulkItemAction, targets []BulkItemTarget, search *ItemSearch, adminUserID int64) ([]*BulkItemResult, error) {
+	// a bulk operation touches many rows, it has more time than a single update
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	if search != nil {
+		targets, err = m.bulkSearchTargets(ctx, tx, *search)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(targets) > MaxBulkItems {
+		return nil, ErrTooManyBulkItems
+	}
+
+	// the items are locked in the same order by every operation to avoid deadlocks
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+
+	var schema []AttributeDefinition
+	if action.Action == BulkMoveItems {
+		query := `
+			SELECT attribute_schema
+			FROM categories
+			WHERE id = $1 AND deleted_at IS NULL
+		`
+
+		err = tx.QueryRow(ctx, query, action.CategoryID).Scan(&schema)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return nil, ErrInvalidBulkTarget
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	queryLock := `
+		SELECT version, attributes, tags
+		FROM items
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	results := []*BulkItemResult{}
+	failed, conflict := false, false
+
+	for _, target := range targets {
+		result := &BulkItemResult{ID: target.ID, Status: bulkStatusOK}
+		results = append(results, result)
+
+		var attributes map[string]any
+		var tags []string
+
+		err = tx.QueryRow(ctx, queryLock, target.ID).Scan(&result.Version, &attributes, &tags)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				result.Status = bulkStatusNotFound
+				failed = true
+				continue
+			default:
+				return nil, err
+			}
+		}
+
+		if target.Version != 0 && target.Version != result.Version {
+			result.Status = bulkStatusConflict
+			failed, conflict = true, true
+			continue
+		}
+
+		// the attributes must be valid in the schema of the new category
+		if action.Action == BulkMoveItems {
+			v := validator.New()
+			if ValidateItemAttributes(v, attributes, schema); !v.Valid() {
+				result.Status = bulkStatusInvalid
+				result.Errors = v.Errors
+				failed = true
+				continue
+			}
+		}
+
+		// the resulting tags must be valid, e.g. not too many
+		if action.Action == BulkAddTags || action.Action == BulkRemoveTags {
+			tags = bulkTags(action, tags)
+
+			v := validator.New()
+			if ValidateTags(v, "tags", tags); !v.Valid() {
+				result.Status = bulkStatusInvalid
+				result.Errors = v.Errors
+				failed = true
+				continue
+			}
+		}
+
+		result.Version, err = m.bulkApply(ctx, tx, action, target.ID, result.Version, tags, adminUserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrEditConflict):
+				result.Status = bulkStatusConflict
+				failed, conflict = true, true
+				continue
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	switch {
+	case conflict:
+		return results, ErrEditConflict
+	case failed:
+		return results, ErrBulkItemsFailed
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Return the items matching the search, at most one more than MaxBulkItems
+func (m *ItemModel) bulkSearchTargets(ctx context.Context, tx pgx.Tx, search ItemSearch) ([]BulkItemTarget, error) {
+	args := search.args()
+
+	query := fmt.Sprintf(`
+		SELECT items.id
+		FROM items
+		WHERE %s
+		ORDER BY items.id ASC
+		LIMIT $%d
+	`, search.conditions(), len(args)+1)
+
+	args = append(args, MaxBulkItems+1)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []BulkItemTarget{}
+	for rows.Next() {
+		var target BulkItemTarget
+		err := rows.Scan(&target.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, target)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// Return the tags of an item after adding or removing the tags of the action,
+// the added tags go after the existing ones
+func bulkTags(action BulkItemAction, tags []string) []string {
+	result := []string{}
+
+	switch action.Action {
+	case BulkAddTags:
+		result = append(result, tags...)
+		for _, tag := range action.Tags {
+			if !validator.PermittedValue(tag, result...) {
+				result = append(result, tag)
+			}
+		}
+	case BulkRemoveTags:
+		for _, tag := range tags {
+			if !validator.PermittedValue(tag, action.Tags...) {
+				result = append(result, tag)
+			}
+		}
+	}
+
+	return result
+}
+
+// Apply the action to a locked item and return its new version. tags are
+// the new tags of the item for the tag actions. The previous state of the
+// updated items is saved as a revision.
+func (m *ItemModel) bulkApply(ctx context.Context, tx pgx.Tx, action BulkItemAction, id int64, version int32, tags []string, adminUserID int64) (int32, error) {
+	var query string
+	var args []any
+
+	switch action.Action {
+	case BulkMoveItems:
+		query = `
+			UPDATE items
+			SET category_id = $2, updated_by = NULLIF($3, 0), version = version + 1,
+				-- an item moved to another category is placed first in it
+				position = CASE WHEN category_id = $2 THEN position
+					ELSE (SELECT COALESCE(MIN(position), 1) - 1 FROM items WHERE category_id = $2)
+				END
+			WHERE id = $1
+			RETURNING version
+		`
+		args = []any{id, action.CategoryID, adminUserID}
+	case BulkAddTags, BulkRemoveTags:
+		query = `
+			UPDATE items
+			SET tags = $2, updated_by = NULLIF($3, 0), version = version + 1
+			WHERE id = $1
+			RETURNING version
+		`
+		args = []any{id, tags, adminUserID}
+	case BulkSetAvailability:
+		query = `
+			UPDATE items
+			SET availability = $2, updated_by = NULLIF($3, 0), version = version + 1
+			WHERE id = $1
+			RETURNING version
+		`
+		args = []any{id, action.Availability, adminUserID}
+	case BulkDeleteItems:
+		// the trashed items keep their version, there is nothing to revise
+		_, err := tx.Exec(ctx, "UPDATE items SET deleted_at = NOW() WHERE id = $1", id)
+		return version, err
+	default:
+		return 0, fmt.Errorf("unknown bulk action %q", action.Action)
+	}
+
+	err := insertItemRevision(ctx, tx, id, version, adminUserID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func ValidateBulkItemAction(v *validator.Validator, action BulkItemAction) {
+	v.Check(validator.PermittedValue(action.Action, BulkItemActions...), "action", "invalid action value")
+
+	switch action.Action {
+	case BulkMoveItems:
+		v.Check(action.CategoryID > 0, "category_id", "must be provided")
+	case BulkAddTags, BulkRemoveTags:
+		v.Check(len(action.Tags) > 0, "tags", "must contain at least 1 tag")
+		ValidateTags(v, "tags", action.Tags)
+	case BulkSetAvailability:
+		v.Check(validator.PermittedValue(action.Availability, ItemAvailabilities...), "availability", "invalid availability value")
+	}
+}
+
+func ValidateBulkItemTargets(v *validator.Validator, targets []BulkItemTarget) {
+	ids := make([]int64, len(targets))
+	for i, target := range targets {
+		ids[i] = target.ID
+		v.Check(target.ID > 0, "items", "must contain positive IDs")
+		v.Check(target.Version >= 0, "items", "must not contain negative versions")
+	}
+
+	v.Check(len(targets) <= MaxBulkItems, "items", fmt.Sprintf("must not contain more than %d items", MaxBulkItems))
+	v.Check(validator.Unique(ids), "items", "must not contain duplicate IDs")
+}
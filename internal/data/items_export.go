@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Time limit for exporting the catalog, longer than the limit of a request
+const ExportTimeout = 5 * time.Minute
+
+// Call fn for every item matching the search, ordered by ID. The rows are
+// read from the connection as they arrive instead of loading every item in
+// memory like List does, so fn should write the item out right away.
+func (m *ItemModel) Export(search ItemSearch, fn func(*Item) error) error {
+	args := search.args()
+
+	query := fmt.Sprintf(`
+		SELECT
+			items.id, COALESCE(item_translations.name, items.name) AS name,
+			COALESCE(item_translations.description, items.description) AS description,
+			items.created_at, items.category_id, items.version, items.price, items.currency,
+			items.availability, items.stock, items.position, items.attributes, items.tags,
+			COALESCE(category_translations.name, categories.name) AS category_name,
+			COALESCE(item_attachments.filename, '') AS filename,
+			COALESCE(item_attachments.key, '') AS key
+		FROM items
+		INNER JOIN categories ON categories.id = items.category_id
+		LEFT JOIN item_attachments on items.id = item_attachments.item_id
+		LEFT JOIN item_translations
+			ON item_translations.item_id = items.id AND item_translations.locale = $%[2]d
+		LEFT JOIN category_translations
+			ON category_translations.category_id = categories.id AND category_translations.locale = $%[2]d
+		WHERE %[1]s
+		ORDER BY items.id ASC
+	`, search.conditions(), len(args)+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExportTimeout)
+	defer cancel()
+
+	args = append(args, search.locale())
+
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+
+		err := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&item.Description,
+			&item.CreatedAt,
+			&item.CategoryID,
+			&item.Version,
+			&item.Price,
+			&item.Currency,
+			&item.Availability,
+			&item.Stock,
+			&item.Position,
+			&item.Attributes,
+			&item.Tags,
+			&item.CategoryName,
+			&item.ItemAttachment.Filename,
+			&item.ItemAttachment.Key,
+		)
+		if err != nil {
+			return err
+		}
+
+		item.ImageURL = m.S3Manager.GetFileUrl(item.ItemAttachment.Key)
+
+		err = fn(&item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
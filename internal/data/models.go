@@ -9,22 +9,54 @@ import (
 )
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound  = errors.New("record not found")
+	ErrEditConflict    = errors.New("edit conflict")
+	ErrTrashedCategory = errors.New("category in the trash")
 )
 
 type Models struct {
+	Backup         BackupModel
 	Categories     CategoryModel
+	CategoryCovers CategoryAttachmentModel
 	Items          ItemModel
 	ItemAttachment ItemAttachmentModel
+	ItemVariants   ItemVariantModel
+	ItemRevisions  ItemRevisionModel
 	AdminUser      AdminUserModel
+	Collections    CollectionModel
+	FeaturedItems  FeaturedItemModel
+	ImportJobs     ImportJobModel
+	Home           HomeModel
+	Suggestions    SuggestionModel
+	Tokens         TokenModel
+	Trash          TrashModel
+	// translations of the name and description in other locales
+	ItemTranslations     TranslationModel
+	CategoryTranslations TranslationModel
 }
 
 func NewModels(db *pgxpool.Pool, s3Manager filestorage.S3) Models {
 	return Models{
+		Backup:         BackupModel{DB: db},
 		Categories:     CategoryModel{DB: db, S3Manager: s3Manager},
+		CategoryCovers: CategoryAttachmentModel{DB: db, S3Manager: s3Manager},
 		Items:          ItemModel{DB: db, S3Manager: s3Manager},
 		ItemAttachment: ItemAttachmentModel{DB: db},
+		ItemVariants:   ItemVariantModel{DB: db, S3Manager: s3Manager},
+		ItemRevisions:  ItemRevisionModel{DB: db},
 		AdminUser:      AdminUserModel{DB: db},
+		Collections:    CollectionModel{DB: db, S3Manager: s3Manager},
+		FeaturedItems:  FeaturedItemModel{DB: db},
+		ImportJobs:     ImportJobModel{DB: db},
+		Home:           HomeModel{DB: db, S3Manager: s3Manager},
+		Suggestions:    SuggestionModel{DB: db},
+		Tokens:         TokenModel{DB: db},
+		Trash:          TrashModel{DB: db, S3Manager: s3Manager},
+		ItemTranslations: TranslationModel{
+			DB: db, table: "item_translations", ownerTable: "items", ownerKey: "item_id",
+		},
+		CategoryTranslations: TranslationModel{
+			DB: db, table: "category_translations", ownerTable: "categories", ownerKey: "category_id",
+		},
 	}
 }
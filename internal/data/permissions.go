@@ -0,0 +1,42 @@
+package data
+
+import "slices"
+
+// Roles of the admin users, from the most to the least privileged
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var Roles = []string{RoleOwner, RoleEditor, RoleViewer}
+
+// Permission codes, e.g. "items:write", checked by the requirePermission
+// middleware
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+// The permissions granted to each role. The editors manage the catalog and
+// the viewers can only read it, only the owners manage the admin users.
+var RolePermissions = map[string]Permissions{
+	RoleOwner: {
+		"items:read", "items:write",
+		"categories:read", "categories:write",
+		"collections:read", "collections:write",
+		"admin_users:read", "admin_users:write",
+	},
+	RoleEditor: {
+		"items:read", "items:write",
+		"categories:read", "categories:write",
+		"collections:read", "collections:write",
+		"admin_users:read",
+	},
+	RoleViewer: {
+		"items:read",
+		"categories:read",
+		"collections:read",
+	},
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// struct to represent a search suggestion, an item or a category
+// with a name similar to the search terms
+type Suggestion struct {
+	Type  string  `json:"type"`
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Score float32 `json:"score"`
+}
+
+type SuggestionModel struct {
+	DB *pgxpool.Pool
+}
+
+// Return the items and categories with a name similar to the search terms,
+// tolerating typos, ordered from the most similar.
+func (m *SuggestionModel) List(q string, limit int) ([]*Suggestion, error) {
+	// word_similarity (<%) matches the terms against any part of the name,
+	// so a partial word like "ceram" works for autocomplete
+	query := `
+		SELECT type, id, name, score
+		FROM (
+			SELECT 'category' AS type, categories.id, categories.name::text AS name,
+				word_similarity(lower($1), lower(categories.name::text)) AS score
+			FROM categories
+			WHERE categories.deleted_at IS NULL AND lower($1) <% lower(categories.name::text)
+			UNION ALL
+			SELECT 'item' AS type, items.id, items.name,
+				word_similarity(lower($1), lower(items.name)) AS score
+			FROM items
+			WHERE items.deleted_at IS NULL AND lower($1) <% lower(items.name)
+		) AS suggestions
+		ORDER BY score DESC, name ASC
+		LIMIT $2
+	`
+
+	// suggestions are requested while typing, so they must be fast
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+
+	for rows.Next() {
+		var suggestion Suggestion
+		err := rows.Scan(
+			&suggestion.Type,
+			&suggestion.ID,
+			&suggestion.Name,
+			&suggestion.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func ValidateSuggestionQuery(v *validator.Validator, q string, limit int) {
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 20, "limit", "must be maximum of 20")
+}
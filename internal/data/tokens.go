@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+const (
+	ScopeActivation    = "activation"
+	ScopePasswordReset = "password-reset"
+)
+
+// A one-time token sent to an admin user. Only the hash is saved, the
+// plaintext is known when the token is created.
+type Token struct {
+	Plaintext   string    `json:"token"`
+	Hash        []byte    `json:"-"`
+	AdminUserID int64     `json:"-"`
+	Expiry      time.Time `json:"expiry"`
+	Scope       string    `json:"-"`
+}
+
+type TokenModel struct {
+	DB *pgxpool.Pool
+}
+
+func generateToken(adminUserID int64, ttl time.Duration, scope string) (*Token, error) {
+	token := &Token{
+		AdminUserID: adminUserID,
+		Expiry:      time.Now().Add(ttl),
+		Scope:       scope,
+	}
+
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 26 characters long, without padding
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
+
+// Create and save a new token for the admin user
+func (m *TokenModel) New(adminUserID int64, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(adminUserID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(token)
+	return token, err
+}
+
+func (m *TokenModel) Insert(token *Token) error {
+	query := `
+		INSERT INTO tokens (hash, admin_user_id, expiry, scope)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	args := []any{token.Hash, token.AdminUserID, token.Expiry, token.Scope}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, query, args...)
+	return err
+}
+
+// Delete the tokens of the admin user with the given scope, once one of them
+// is used the others are no longer valid
+func (m *TokenModel) DeleteAllForAdminUser(scope string, adminUserID int64) error {
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1 AND admin_user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, query, scope, adminUserID)
+	return err
+}
+
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+}
@@ -0,0 +1,152 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jesusangelm/api_galeria/internal/validator"
+)
+
+// The content stored in the items and categories tables is in the default
+// locale, the other locales are stored in the translations tables.
+const DefaultLocale = "es"
+
+var Locales = []string{"es", "en"}
+
+// struct to represent the translation of the name and description
+// of an item or a category
+type Translation struct {
+	Locale      string `json:"locale"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TranslationModel works with the translations of one kind of record,
+// e.g. the item_translations table for the items table.
+type TranslationModel struct {
+	DB         *pgxpool.Pool
+	table      string
+	ownerTable string
+	ownerKey   string
+}
+
+// Return all the translations of the record with the given ID.
+// ErrRecordNotFound is returned when the record does not exist.
+func (m *TranslationModel) GetAll(ownerID int64) ([]*Translation, error) {
+	if ownerID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s.locale, %[1]s.name, %[1]s.description
+		FROM %[2]s
+		LEFT JOIN %[1]s ON %[1]s.%[3]s = %[2]s.id
+		WHERE %[2]s.id = $1 AND %[2]s.deleted_at IS NULL
+		ORDER BY %[1]s.locale
+	`, m.table, m.ownerTable, m.ownerKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := false
+	translations := []*Translation{}
+
+	for rows.Next() {
+		found = true
+
+		// the record exists but may not have translations
+		var locale, name, description *string
+		err := rows.Scan(&locale, &name, &description)
+		if err != nil {
+			return nil, err
+		}
+		if locale == nil {
+			continue
+		}
+
+		translations = append(translations, &Translation{
+			Locale:      *locale,
+			Name:        *name,
+			Description: *description,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	return translations, nil
+}
+
+// Insert or replace the translation of the record with the given ID.
+// ErrRecordNotFound is returned when the record does not exist.
+func (m *TranslationModel) Upsert(ownerID int64, translation *Translation) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, locale, name, description)
+		SELECT id, $2, $3, $4 FROM %[3]s WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (%[2]s, locale)
+		DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description
+	`, m.table, m.ownerKey, m.ownerTable)
+
+	args := []any{ownerID, translation.Locale, translation.Name, translation.Description}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *TranslationModel) Delete(ownerID int64, locale string) error {
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		USING %[3]s
+		WHERE %[1]s.%[2]s = $1 AND %[1]s.locale = $2
+		AND %[3]s.id = %[1]s.%[2]s AND %[3]s.deleted_at IS NULL
+	`, m.table, m.ownerKey, m.ownerTable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, ownerID, locale)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateTranslation(v *validator.Validator, translation *Translation) {
+	v.Check(validator.PermittedValue(translation.Locale, Locales...), "locale", "invalid locale value")
+	v.Check(translation.Locale != DefaultLocale, "locale", "the default locale is set on the record itself")
+
+	v.Check(translation.Name != "", "name", "must be provided")
+	v.Check(len(translation.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(translation.Description != "", "description", "must be provided")
+	v.Check(len(translation.Description) <= 500, "description", "must not be more than 500 bytes long")
+}
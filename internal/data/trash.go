@@ -0,0 +1,178 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	filestorage "github.com/jesusangelm/api_galeria/internal/file_storage"
+)
+
+// struct to represent a trashed item or category
+type TrashEntry struct {
+	Type       string    `json:"type"` // item or category
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	CategoryID int64     `json:"category_id,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+type TrashModel struct {
+	DB        *pgxpool.Pool
+	S3Manager filestorage.S3
+}
+
+// Return a page of the trashed items and categories, sorted by the deletion
+// time. Items and categories share the ids, so there is no cursor, only pages.
+func (m *TrashModel) List(filters Filters) ([]*TrashEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, type, id, name, category_id, deleted_at
+		FROM (
+			SELECT 'category' AS type, categories.id, categories.name::text AS name,
+				0::bigint AS category_id, categories.deleted_at
+			FROM categories
+			WHERE categories.deleted_at IS NOT NULL
+			UNION ALL
+			SELECT 'item' AS type, items.id, items.name, items.category_id, items.deleted_at
+			FROM items
+			WHERE items.deleted_at IS NOT NULL
+		) AS trash
+		ORDER BY %s %s, type ASC, id ASC
+		LIMIT $1
+		OFFSET $2
+	`, filters.totalRecordsExpr(), filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*TrashEntry{}
+
+	for rows.Next() {
+		var entry TrashEntry
+		err := rows.Scan(
+			&totalRecords,
+			&entry.Type,
+			&entry.ID,
+			&entry.Name,
+			&entry.CategoryID,
+			&entry.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// Permanently delete the items and categories trashed longer than the given
+// retention, along with their files in S3. The items of a purged category are
+// purged with it. Return the number of purged items and categories, along
+// with the errors of the files that could not be deleted.
+func (m *TrashModel) Purge(retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+
+	// the keys are collected before deleting the rows, the attachments
+	// are removed from the DB by the ON DELETE CASCADE
+	queryKeys := `
+		SELECT item_attachments.key
+		FROM item_attachments
+		INNER JOIN items ON items.id = item_attachments.item_id
+		INNER JOIN categories ON categories.id = items.category_id
+		WHERE items.deleted_at < $1 OR categories.deleted_at < $1
+		UNION ALL
+		SELECT category_attachments.key
+		FROM category_attachments
+		INNER JOIN categories ON categories.id = category_attachments.category_id
+		WHERE categories.deleted_at < $1
+	`
+
+	queryItems := `
+		DELETE FROM items
+		WHERE deleted_at < $1
+	`
+
+	queryCategories := `
+		DELETE FROM categories
+		WHERE deleted_at < $1
+	`
+
+	// the purge runs in the background, it has more time than a request
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// Rollback is a no-op after a successful Commit
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, queryKeys, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		keys = append(keys, key)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	itemsResult, err := tx.Exec(ctx, queryItems, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	categoriesResult, err := tx.Exec(ctx, queryCategories, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Delete from S3 the files of the purged rows. The rows are already
+	// gone, so a failing file does not stop the others, the failures are
+	// returned together at the end.
+	var deleteErrs []error
+	for _, key := range keys {
+		err = m.S3Manager.DeleteFile(key)
+		if err != nil {
+			deleteErrs = append(deleteErrs, fmt.Errorf("deleting %s: %w", key, err))
+		}
+	}
+
+	return itemsResult.RowsAffected() + categoriesResult.RowsAffected(), errors.Join(deleteErrs...)
+}
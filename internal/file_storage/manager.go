@@ -2,16 +2,25 @@ package filestorage
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -22,6 +31,16 @@ type S3 struct {
 	Bucket  string
 }
 
+var ErrFileNotFound = errors.New("file not found")
+
+// Size and checksum of a file in the bucket. SHA256 is only known for the
+// files uploaded with PutFileVerified.
+type FileInfo struct {
+	Size        int64
+	ContentType string
+	SHA256      string
+}
+
 type AttachmentInfo struct {
 	Key         string
 	Filename    string
@@ -84,7 +103,6 @@ func (s *S3) UploadFileFromPath(filePath string) (*AttachmentInfo, error) {
 }
 
 func (s *S3) UploadFile(fileMem multipart.File, handler multipart.FileHeader) (*AttachmentInfo, error) {
-	session := s.Session
 	fileSize := handler.Size
 
 	buffer := make([]byte, fileSize)
@@ -93,13 +111,21 @@ func (s *S3) UploadFile(fileMem multipart.File, handler multipart.FileHeader) (*
 		return nil, err
 	}
 
+	return s.UploadBuffer(buffer, handler.Filename)
+}
+
+// Upload the content with a random key, filename is only kept as metadata
+func (s *S3) UploadBuffer(buffer []byte, filename string) (*AttachmentInfo, error) {
+	session := s.Session
+	fileSize := int64(len(buffer))
+
 	fileType := http.DetectContentType(buffer)
 
 	uploader := s3manager.NewUploader(session)
 
 	// Experimental key generation
 	randomBytes := make([]byte, 16)
-	_, err = rand.Read(randomBytes)
+	_, err := rand.Read(randomBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +143,7 @@ func (s *S3) UploadFile(fileMem multipart.File, handler multipart.FileHeader) (*
 
 	attachment := AttachmentInfo{
 		Key:         key,
-		Filename:    filepath.Base(handler.Filename),
+		Filename:    filepath.Base(filename),
 		ContentType: fileType,
 		ByteSize:    fileSize,
 		ETag:        *result.ETag,
@@ -148,6 +174,86 @@ func (s *S3) GetFileUrl(key string) string {
 	return url
 }
 
+// Upload the content with the given key, replacing the file if it exists
+func (s *S3) PutFile(key string, buffer []byte) error {
+	uploader := s3manager.NewUploader(s.Session)
+
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Body:        bytes.NewReader(buffer),
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(http.DetectContentType(buffer)),
+	})
+
+	return err
+}
+
+// Upload the content with the given key, the bucket checks its MD5 and
+// its SHA-256 checksum is saved in the metadata of the file
+func (s *S3) PutFileVerified(ctx context.Context, key string, buffer []byte, contentType string) error {
+	svc := s3.New(s.Session)
+
+	md5Sum := md5.Sum(buffer)
+	sha256Sum := sha256.Sum256(buffer)
+
+	_, err := svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Body:        bytes.NewReader(buffer),
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		ContentMD5:  aws.String(base64.StdEncoding.EncodeToString(md5Sum[:])),
+		Metadata:    map[string]*string{"Sha256": aws.String(hex.EncodeToString(sha256Sum[:]))},
+	})
+
+	return err
+}
+
+// Return the size and checksum of the file, ErrFileNotFound when it does not exist
+func (s *S3) HeadFile(ctx context.Context, key string) (*FileInfo, error) {
+	svc := s3.New(s.Session)
+
+	result, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	info := FileInfo{
+		Size:        aws.Int64Value(result.ContentLength),
+		ContentType: aws.StringValue(result.ContentType),
+	}
+
+	// the case of the metadata keys depends on the storage backend
+	for name, value := range result.Metadata {
+		if strings.EqualFold(name, "Sha256") {
+			info.SHA256 = aws.StringValue(value)
+		}
+	}
+
+	return &info, nil
+}
+
+// Return the content of the file, the caller must close it
+func (s *S3) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	svc := s3.New(s.Session)
+
+	result, err := svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Body, nil
+}
+
 func (s *S3) DeleteFile(key string) error {
 	svc := s3.New(s.Session)
 
@@ -0,0 +1,210 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"sync"
+	ttemplate "text/template"
+	"time"
+)
+
+// The templates are named <name>.<locale>.tmpl, e.g. password_reset.en.tmpl,
+// and define a "subject", a "plainBody" and an "htmlBody" template.
+//
+//go:embed "templates"
+var templateFS embed.FS
+
+// Locale used when there is no template for the requested one
+const defaultLocale = "es"
+
+// Sends the emails rendered from the templates
+type Mailer interface {
+	Send(recipient, locale, templateName string, data any) error
+}
+
+// An email rendered from a template
+type Message struct {
+	Recipient string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// Render the template in the given locale, or in the default locale when
+// the template is not translated
+func Render(recipient, locale, templateName string, data any) (*Message, error) {
+	filename := fmt.Sprintf("templates/%s.%s.tmpl", templateName, locale)
+	if _, err := templateFS.Open(filename); err != nil {
+		filename = fmt.Sprintf("templates/%s.%s.tmpl", templateName, defaultLocale)
+	}
+
+	// the subject and the plain body are not HTML, so they are not escaped
+	textTmpl, err := ttemplate.New("email").ParseFS(templateFS, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Recipient: recipient}
+
+	subject := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+	msg.Subject = subject.String()
+
+	plainBody := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return nil, err
+	}
+	msg.PlainBody = plainBody.String()
+
+	htmlTmpl, err := template.New("email").ParseFS(templateFS, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return nil, err
+	}
+	msg.HTMLBody = htmlBody.String()
+
+	return msg, nil
+}
+
+// Sends the emails through an SMTP server
+type SMTP struct {
+	addr   string
+	auth   smtp.Auth
+	sender string
+}
+
+// Return a mailer for the SMTP server, the sender is the From address,
+// e.g. "Galeria <no-reply@example.com>"
+func NewSMTP(host string, port int, username, password, sender string) *SMTP {
+	return &SMTP{
+		addr:   host + ":" + strconv.Itoa(port),
+		auth:   smtp.PlainAuth("", username, password, host),
+		sender: sender,
+	}
+}
+
+func (m *SMTP) Send(recipient, locale, templateName string, data any) error {
+	msg, err := Render(recipient, locale, templateName, data)
+	if err != nil {
+		return err
+	}
+
+	body, err := msg.mime(m.sender)
+	if err != nil {
+		return err
+	}
+
+	from, err := mailAddress(m.sender)
+	if err != nil {
+		return err
+	}
+
+	// try a few times, the server may be temporarily unavailable
+	for i := 1; i <= 3; i++ {
+		err = smtp.SendMail(m.addr, m.auth, from, []string{recipient}, body)
+		if err == nil {
+			return nil
+		}
+
+		if i < 3 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	return err
+}
+
+// Writes the emails to a file or the log instead of sending them, for
+// development and tests
+type FileSink struct {
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func NewFileSink(out io.Writer) *FileSink {
+	return &FileSink{out: out}
+}
+
+func (m *FileSink) Send(recipient, locale, templateName string, data any) error {
+	msg, err := Render(recipient, locale, templateName, data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err = fmt.Fprintf(m.out, "To: %s\nSubject: %s\n\n%s\n", msg.Recipient, msg.Subject, msg.PlainBody)
+	return err
+}
+
+// Return the message as a multipart/alternative email with the plain and
+// the HTML bodies
+func (msg *Message) mime(sender string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.PlainBody},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.WriteString(pw, part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// the subject may not be ASCII, e.g. "Restablecimiento de contraseña"
+	fmt.Fprintf(buf, "From: %s\r\n", sender)
+	fmt.Fprintf(buf, "To: %s\r\n", msg.Recipient)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// Return the address of the sender, without the name
+func mailAddress(sender string) (string, error) {
+	addr, err := mail.ParseAddress(sender)
+	if err != nil {
+		return "", err
+	}
+
+	return addr.Address, nil
+}
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+DROP FUNCTION IF EXISTS categories_search_vector_trigger;
+DROP TRIGGER IF EXISTS items_search_vector_update ON items;
+DROP FUNCTION IF EXISTS items_search_vector_trigger;
+DROP FUNCTION IF EXISTS items_search_vector;
+DROP INDEX IF EXISTS items_search_vector_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS search_vector;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS es_unaccent;
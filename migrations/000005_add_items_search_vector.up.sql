@@ -0,0 +1,47 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- Spanish configuration that ignores accents, so "ceramica" matches "Cerámica"
+CREATE TEXT SEARCH CONFIGURATION es_unaccent ( COPY = spanish );
+ALTER TEXT SEARCH CONFIGURATION es_unaccent
+  ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+ALTER TABLE items ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION items_search_vector(item_name text, item_description text, item_category_id bigint)
+RETURNS tsvector AS $$
+  SELECT
+    setweight(to_tsvector('es_unaccent', coalesce(item_name, '')), 'A') ||
+    setweight(to_tsvector('es_unaccent', coalesce(item_description, '')), 'B') ||
+    setweight(to_tsvector('es_unaccent', coalesce(
+      (SELECT categories.name::text FROM categories WHERE categories.id = item_category_id), ''
+    )), 'C')
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION items_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector := items_search_vector(NEW.name, NEW.description, NEW.category_id);
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER items_search_vector_update
+  BEFORE INSERT OR UPDATE OF name, description, category_id ON items
+  FOR EACH ROW EXECUTE FUNCTION items_search_vector_trigger();
+
+-- Keep the items search vector in sync when a category is renamed
+CREATE OR REPLACE FUNCTION categories_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+  UPDATE items
+  SET search_vector = items_search_vector(items.name, items.description, items.category_id)
+  WHERE items.category_id = NEW.id;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER categories_search_vector_update
+  AFTER UPDATE OF name ON categories
+  FOR EACH ROW EXECUTE FUNCTION categories_search_vector_trigger();
+
+UPDATE items SET search_vector = items_search_vector(name, description, category_id);
+
+CREATE INDEX IF NOT EXISTS items_search_vector_idx ON items USING GIN (search_vector);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS items_price_idx;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_availability_check;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_stock_check;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_price_check;
+ALTER TABLE items DROP COLUMN IF EXISTS stock;
+ALTER TABLE items DROP COLUMN IF EXISTS availability;
+ALTER TABLE items DROP COLUMN IF EXISTS currency;
+ALTER TABLE items DROP COLUMN IF EXISTS price;
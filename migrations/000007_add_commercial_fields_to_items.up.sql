@@ -0,0 +1,11 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS price bigint NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS availability text NOT NULL DEFAULT 'available';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS stock integer NOT NULL DEFAULT 0;
+
+ALTER TABLE items ADD CONSTRAINT items_price_check CHECK (price >= 0);
+ALTER TABLE items ADD CONSTRAINT items_stock_check CHECK (stock >= 0);
+ALTER TABLE items ADD CONSTRAINT items_availability_check
+  CHECK (availability IN ('available', 'sold', 'made-to-order'));
+
+CREATE INDEX IF NOT EXISTS items_price_idx ON items (price);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS item_variants;
+ALTER TABLE item_attachments DROP CONSTRAINT IF EXISTS item_attachments_id_item_id_key;
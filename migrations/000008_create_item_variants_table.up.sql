@@ -0,0 +1,20 @@
+-- allows item_variants to reference an attachment of the same item
+ALTER TABLE item_attachments ADD CONSTRAINT item_attachments_id_item_id_key UNIQUE (id, item_id);
+
+CREATE TABLE IF NOT EXISTS item_variants (
+  id bigserial PRIMARY KEY,
+  item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+  sku citext UNIQUE NOT NULL,
+  size text NOT NULL DEFAULT '',
+  color text NOT NULL DEFAULT '',
+  material text NOT NULL DEFAULT '',
+  price bigint CHECK (price >= 0),
+  stock integer NOT NULL DEFAULT 0 CHECK (stock >= 0),
+  item_attachment_id bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  version integer NOT NULL DEFAULT 1,
+  CONSTRAINT item_variants_item_attachment_fkey FOREIGN KEY (item_attachment_id, item_id)
+    REFERENCES item_attachments (id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS item_variants_item_id_idx ON item_variants (item_id);
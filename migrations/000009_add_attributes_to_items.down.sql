@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_attributes_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS attributes;
+ALTER TABLE categories DROP COLUMN IF EXISTS attribute_schema;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS item_translations;
+DROP TABLE IF EXISTS category_translations;
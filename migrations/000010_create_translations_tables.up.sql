@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS item_translations (
+  item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+  locale text NOT NULL,
+  name text NOT NULL,
+  description text NOT NULL,
+  PRIMARY KEY (item_id, locale)
+);
+
+CREATE TABLE IF NOT EXISTS category_translations (
+  category_id bigint NOT NULL REFERENCES categories ON DELETE CASCADE,
+  locale text NOT NULL,
+  name text NOT NULL,
+  description text NOT NULL,
+  PRIMARY KEY (category_id, locale)
+);
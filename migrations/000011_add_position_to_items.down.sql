@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS items_category_id_position_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS position;
@@ -0,0 +1,11 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS position integer NOT NULL DEFAULT 0;
+
+-- keep the current order of the categories, newest items first
+UPDATE items SET position = ordered.position
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY category_id ORDER BY created_at DESC, id DESC) AS position
+  FROM items
+) AS ordered
+WHERE items.id = ordered.id;
+
+CREATE INDEX IF NOT EXISTS items_category_id_position_idx ON items (category_id, position);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS collections (
+  id bigserial PRIMARY KEY,
+  name citext UNIQUE NOT NULL,
+  description text NOT NULL,
+  cover_attachment_id bigint REFERENCES item_attachments ON DELETE SET NULL,
+  published bool NOT NULL DEFAULT false,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+  collection_id bigint NOT NULL REFERENCES collections ON DELETE CASCADE,
+  item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+  position integer NOT NULL,
+  PRIMARY KEY (collection_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS collection_items_item_id_idx ON collection_items (item_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS featured_items (
+  item_id bigint PRIMARY KEY REFERENCES items ON DELETE CASCADE,
+  priority integer NOT NULL DEFAULT 0,
+  starts_at timestamp(0) with time zone,
+  ends_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  CONSTRAINT featured_items_dates_check CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS featured_items_priority_idx ON featured_items (priority DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE categories DROP COLUMN IF EXISTS cover_item_attachment_id;
+DROP TABLE IF EXISTS category_attachments;
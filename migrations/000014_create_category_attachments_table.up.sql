@@ -0,0 +1,14 @@
+-- a category cover is either an uploaded image (category_attachments)
+-- or the image of one of its items (cover_item_attachment_id)
+CREATE TABLE IF NOT EXISTS category_attachments (
+  id bigserial PRIMARY KEY,
+  key text NOT NULL,
+  filename text NOT NULL,
+  content_type text NOT NULL,
+  byte_size bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  category_id bigint UNIQUE NOT NULL REFERENCES categories ON DELETE CASCADE
+);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS cover_item_attachment_id bigint
+  REFERENCES item_attachments ON DELETE SET NULL;
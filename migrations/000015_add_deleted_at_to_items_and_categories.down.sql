@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS categories_name_idx;
+DROP INDEX IF EXISTS categories_deleted_at_idx;
+DROP INDEX IF EXISTS items_deleted_at_idx;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE items DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
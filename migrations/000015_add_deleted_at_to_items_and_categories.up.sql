@@ -0,0 +1,10 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+
+-- the trash and the retention job look for the trashed rows
+CREATE INDEX IF NOT EXISTS items_deleted_at_idx ON items (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS categories_deleted_at_idx ON categories (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a trashed category does not hold its name, only the live ones are unique
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_idx ON categories (name) WHERE deleted_at IS NULL;
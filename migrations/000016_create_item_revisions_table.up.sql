@@ -0,0 +1,10 @@
+-- previous states of the items, one row per successful update
+CREATE TABLE IF NOT EXISTS item_revisions (
+  id bigserial PRIMARY KEY,
+  item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+  version integer NOT NULL,
+  snapshot jsonb NOT NULL,
+  admin_user_id bigint REFERENCES admin_users ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  UNIQUE (item_id, version)
+);
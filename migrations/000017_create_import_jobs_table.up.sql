@@ -0,0 +1,13 @@
+-- catalog imports run in the background, the report is filled row by row
+CREATE TABLE IF NOT EXISTS import_jobs (
+  id bigserial PRIMARY KEY,
+  status text NOT NULL DEFAULT 'pending',
+  filename text NOT NULL,
+  total_rows integer NOT NULL DEFAULT 0,
+  processed_rows integer NOT NULL DEFAULT 0,
+  report jsonb NOT NULL DEFAULT '[]',
+  error text NOT NULL DEFAULT '',
+  admin_user_id bigint REFERENCES admin_users ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  finished_at timestamp(0) with time zone
+);
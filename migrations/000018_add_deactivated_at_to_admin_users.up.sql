@@ -0,0 +1,2 @@
+-- a deactivated admin user can not log in nor use its tokens
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS deactivated_at timestamp(0) with time zone;
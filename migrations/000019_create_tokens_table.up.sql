@@ -0,0 +1,11 @@
+-- one-time tokens, only the SHA-256 hash of the plaintext token is stored
+CREATE TABLE IF NOT EXISTS tokens (
+  hash bytea PRIMARY KEY,
+  admin_user_id bigint NOT NULL REFERENCES admin_users ON DELETE CASCADE,
+  expiry timestamp(0) with time zone NOT NULL,
+  scope text NOT NULL
+);
+
+-- the activated flag was not checked before, the existing admin users were
+-- able to log in and keep doing it
+UPDATE admin_users SET activated = true;
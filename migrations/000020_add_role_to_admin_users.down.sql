@@ -0,0 +1 @@
+ALTER TABLE admin_users DROP COLUMN IF EXISTS role;
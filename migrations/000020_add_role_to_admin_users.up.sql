@@ -0,0 +1,5 @@
+-- owner, editor or viewer, see data.RolePermissions
+ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'viewer';
+
+-- every admin user was able to do everything, they keep doing it
+UPDATE admin_users SET role = 'owner';
@@ -0,0 +1,4 @@
+ALTER TABLE categories DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE categories DROP COLUMN IF EXISTS created_by;
+ALTER TABLE items DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE items DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,5 @@
+-- admin users who created and last updated the rows, NULL when unknown
+ALTER TABLE items ADD COLUMN IF NOT EXISTS created_by bigint REFERENCES admin_users ON DELETE SET NULL;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS updated_by bigint REFERENCES admin_users ON DELETE SET NULL;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS created_by bigint REFERENCES admin_users ON DELETE SET NULL;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS updated_by bigint REFERENCES admin_users ON DELETE SET NULL;
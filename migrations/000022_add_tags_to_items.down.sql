@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS items_tags_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS tags;
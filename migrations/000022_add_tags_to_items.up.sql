@@ -0,0 +1,4 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+
+-- the listing filters the items having all the given tags
+CREATE INDEX IF NOT EXISTS items_tags_idx ON items USING GIN (tags);